@@ -0,0 +1,97 @@
+// Command export-map converts a map (.map, .osm or .osm.pbf) to the .map format, GeoJSON or Graphviz DOT, so it can
+// be inspected in external tools or diffed in review.
+//
+// Usage:
+//
+//	export-map -format geojson -o town.geojson assets/maps/1.map
+//	export-map -format dot assets/maps/1.map | neato -n -Tpng > map.png
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/EtienneBruines/ultimate-dispatcher/dl"
+)
+
+func main() {
+	format := flag.String("format", "yaml", "the format to write: yaml, geojson or dot")
+	output := flag.String("o", "", "the file to write to, instead of standard output")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] file\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var write func(*dl.Map, io.Writer) error
+	switch *format {
+	case "yaml":
+		write = (*dl.Map).WriteYAML
+	case "geojson":
+		write = (*dl.Map).WriteGeoJSON
+	case "dot":
+		write = (*dl.Map).WriteDOT
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format: %s\n", *format)
+		os.Exit(2)
+	}
+
+	m, err := readMap(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		w = f
+	}
+
+	if err := write(m, w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// Writing to the file may only fail once it's closed
+	if *output != "" {
+		if err := w.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func readMap(filename string) (*dl.Map, error) {
+	switch filepath.Ext(filename) {
+	case ".osm", ".pbf":
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if filepath.Ext(filename) == ".pbf" {
+			return dl.ReadOSMPBF(f, dl.DefaultOSMOptions)
+		}
+		return dl.ReadOSM(f, dl.DefaultOSMOptions)
+	default:
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return dl.ParseMap(b, false)
+	}
+}
@@ -0,0 +1,54 @@
+// Command generate-map writes a procedurally generated city to a .map file.
+//
+// Usage:
+//
+//	generate-map -seed 42 -o assets/maps/generated.map
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/EtienneBruines/ultimate-dispatcher/dl"
+)
+
+func main() {
+	opts := dl.DefaultCityOptions(0)
+	flag.Int64Var(&opts.Seed, "seed", opts.Seed, "the seed of the random generator")
+	width := flag.Float64("width", float64(opts.Width), "the width of the city")
+	height := flag.Float64("height", float64(opts.Height), "the height of the city")
+	blockSize := flag.Float64("block", float64(opts.BlockSize), "the distance between streets in dense districts")
+	flag.IntVar(&opts.Districts, "districts", opts.Districts, "the number of districts")
+	flag.BoolVar(&opts.River, "river", opts.River, "whether or not a river flows through the city")
+	flag.IntVar(&opts.Bridges, "bridges", opts.Bridges, "the number of bridges crossing the river")
+	flag.BoolVar(&opts.RingRoad, "ring", opts.RingRoad, "whether or not there's a ring road around the center")
+	output := flag.String("o", "", "the file to write to, instead of standard output")
+	flag.Parse()
+
+	opts.Width, opts.Height, opts.BlockSize = float32(*width), float32(*height), float32(*blockSize)
+
+	m := dl.GenerateCity(opts)
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		w = f
+	}
+
+	if err := m.WriteYAML(w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// Writing to the file may only fail once it's closed
+	if *output != "" {
+		if err := w.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
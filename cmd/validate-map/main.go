@@ -0,0 +1,64 @@
+// Command validate-map checks .map files for problems, so they can be fixed before the game tries to load them.
+//
+// Usage:
+//
+//	validate-map [-strict=false] assets/maps/*.map
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/EtienneBruines/ultimate-dispatcher/dl"
+)
+
+func main() {
+	strict := flag.Bool("strict", true, "report fields which are not part of the map format")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] file.map...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var failed bool
+	for _, filename := range flag.Args() {
+		if !validate(filename, *strict) {
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func validate(filename string, strict bool) bool {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	m, err := dl.ParseMap(b, strict)
+	if err == nil {
+		fmt.Printf("%s: OK (%d nodes)\n", filename, len(m.Nodes))
+		return true
+	}
+
+	if errs, ok := err.(dl.MapErrors); ok {
+		for _, e := range errs {
+			fmt.Printf("%s:%v\n", filename, e)
+		}
+		return false
+	}
+
+	fmt.Printf("%s: %v\n", filename, err)
+	return false
+}
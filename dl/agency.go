@@ -0,0 +1,158 @@
+package dl
+
+import "strings"
+
+// Agency is the service a unit belongs to
+type Agency uint8
+
+const (
+	AgencyPolice Agency = iota
+	AgencyFire
+	AgencyMedical
+	AgencyTowing
+)
+
+var agencyNames = nameTable[Agency]{typeName: "Agency", what: "agency", names: map[Agency]string{
+	AgencyPolice:  "police",
+	AgencyFire:    "fire",
+	AgencyMedical: "medical",
+	AgencyTowing:  "towing",
+}}
+
+// agencyBases are the kinds of points of interest the units of an agency start at and return to
+var agencyBases = map[Agency]POIKind{
+	AgencyPolice:  POIStation,
+	AgencyFire:    POIFireStation,
+	AgencyMedical: POIHospital,
+	AgencyTowing:  POIDepot,
+}
+
+func (a Agency) String() string {
+	return agencyNames.name(a)
+}
+
+func ParseAgency(s string) (Agency, error) {
+	return agencyNames.parse(s)
+}
+
+// Base returns the kind of points of interest the units of the agency are based at, and false if they have none
+func (a Agency) Base() (POIKind, bool) {
+	kind, ok := agencyBases[a]
+	return kind, ok
+}
+
+func (a Agency) MarshalYAML() (interface{}, error) {
+	return a.String(), nil
+}
+
+func (a *Agency) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return agencyNames.unmarshalYAML(unmarshal, a)
+}
+
+// UnmarshalText is used by the JSON and TOML unit files
+func (a *Agency) UnmarshalText(b []byte) error {
+	return agencyNames.unmarshalText(b, a)
+}
+
+// Capability is something a unit can do at an incident
+type Capability uint8
+
+const (
+	CapabilityArrest Capability = iota
+	CapabilityMedical
+	CapabilityFire
+	CapabilityTowing
+	CapabilityK9
+	CapabilitySWAT
+)
+
+var capabilityNames = nameTable[Capability]{typeName: "Capability", what: "capability", names: map[Capability]string{
+	CapabilityArrest:  "arrest",
+	CapabilityMedical: "medical",
+	CapabilityFire:    "fire",
+	CapabilityTowing:  "towing",
+	CapabilityK9:      "k9",
+	CapabilitySWAT:    "swat",
+}}
+
+func (c Capability) String() string {
+	return capabilityNames.name(c)
+}
+
+func ParseCapability(s string) (Capability, error) {
+	return capabilityNames.parse(s)
+}
+
+func (c Capability) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+func (c *Capability) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return capabilityNames.unmarshalYAML(unmarshal, c)
+}
+
+// UnmarshalText is used by the JSON and TOML unit files
+func (c *Capability) UnmarshalText(b []byte) error {
+	return capabilityNames.unmarshalText(b, c)
+}
+
+type Capabilities []Capability
+
+func (c Capabilities) Has(capability Capability) bool {
+	for _, own := range c {
+		if own == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Capabilities) String() string {
+	names := make([]string, len(c))
+	for i, capability := range c {
+		names[i] = capability.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// CapabilityRequirer is an incident which can only be resolved once units with all of the capabilities are there.
+// Incidents which aren't can be resolved by any unit.
+type CapabilityRequirer interface {
+	Requires() Capabilities
+}
+
+// Helps indicates whether or not a unit with the capabilities can do anything about the incident
+func (c Capabilities) Helps(in Incident) bool {
+	requirer, ok := in.(CapabilityRequirer)
+	if !ok {
+		return true
+	}
+	for _, required := range requirer.Requires() {
+		if c.Has(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingCapabilities returns the capabilities the incident requires which none of the units have
+func MissingCapabilities(in Incident, units []*PoliceComponent) Capabilities {
+	requirer, ok := in.(CapabilityRequirer)
+	if !ok {
+		return nil
+	}
+	var missing Capabilities
+	for _, required := range requirer.Requires() {
+		found := false
+		for _, unit := range units {
+			if unit.Unit.Capabilities.Has(required) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,94 @@
+package dl
+
+import (
+	"testing"
+
+	"engo.io/engo"
+)
+
+// anyoneIncident is an incident which doesn't require any capabilities
+type anyoneIncident struct{}
+
+func (anyoneIncident) Type() string            { return "anyoneIncident" }
+func (anyoneIncident) Update(float32)          {}
+func (anyoneIncident) SetLocation(*engo.Point) {}
+func (anyoneIncident) Resolved() int           { return 0 }
+func (anyoneIncident) Reward() int             { return 0 }
+func (anyoneIncident) Penalty() int            { return 0 }
+
+func TestCapabilitiesHelps(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities Capabilities
+		incident     Incident
+		helps        bool
+	}{
+		{"police at a robbery", Capabilities{CapabilityArrest}, &IncidentRobbery{}, true},
+		{"ambulance at a robbery", Capabilities{CapabilityMedical}, &IncidentRobbery{}, false},
+		{"ambulance at an accident", Capabilities{CapabilityMedical}, &IncidentCarAccident{}, true},
+		{"tow truck at an accident", Capabilities{CapabilityTowing}, &IncidentCarAccident{}, true},
+		{"police at an accident", Capabilities{CapabilityArrest}, &IncidentCarAccident{}, false},
+		{"K9 unit at a fire", Capabilities{CapabilityArrest, CapabilityK9}, &IncidentFire{}, false},
+		{"fire engine at a fire", Capabilities{CapabilityFire}, &IncidentFire{}, true},
+		{"no capabilities at a fire", nil, &IncidentFire{}, false},
+		{"no capabilities at anything else", nil, anyoneIncident{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if helps := tt.capabilities.Helps(tt.incident); helps != tt.helps {
+				t.Fatalf("Helps is %v, expected %v", helps, tt.helps)
+			}
+		})
+	}
+}
+
+func TestMissingCapabilities(t *testing.T) {
+	unit := func(capabilities ...Capability) *PoliceComponent {
+		return &PoliceComponent{Unit: UnitType{Capabilities: capabilities}}
+	}
+	tests := []struct {
+		name     string
+		incident Incident
+		units    []*PoliceComponent
+		missing  Capabilities
+	}{
+		{"nobody at an accident", &IncidentCarAccident{}, nil, Capabilities{CapabilityMedical, CapabilityTowing}},
+		{"ambulance at an accident", &IncidentCarAccident{}, []*PoliceComponent{unit(CapabilityMedical)},
+			Capabilities{CapabilityTowing}},
+		{"ambulance and tow truck at an accident", &IncidentCarAccident{},
+			[]*PoliceComponent{unit(CapabilityTowing), unit(CapabilityMedical)}, nil},
+		{"police at a fire", &IncidentFire{}, []*PoliceComponent{unit(CapabilityArrest), unit(CapabilitySWAT)},
+			Capabilities{CapabilityFire, CapabilityMedical}},
+		{"K9 unit at a robbery", &IncidentRobbery{}, []*PoliceComponent{unit(CapabilityArrest, CapabilityK9)}, nil},
+		{"nobody at anything else", anyoneIncident{}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := MissingCapabilities(tt.incident, tt.units)
+			if len(missing) != len(tt.missing) {
+				t.Fatalf("missing %v, expected %v", missing, tt.missing)
+			}
+			for i := range missing {
+				if missing[i] != tt.missing[i] {
+					t.Fatalf("missing %v, expected %v", missing, tt.missing)
+				}
+			}
+		})
+	}
+}
+
+func TestAgencyBase(t *testing.T) {
+	m := GenerateCity(DefaultCityOptions(1))
+	m.Initialize()
+	for agency := range agencyNames.names {
+		kind, ok := agency.Base()
+		if !ok {
+			t.Fatalf("the %s agency has no base", agency)
+		}
+		if len(m.POIsOf(kind)) == 0 {
+			t.Fatalf("the generated city has no %s for the %s agency", kind, agency)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package dl
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"engo.io/engo"
+	"github.com/luxengine/math"
+)
+
+// CityOptions configures the procedurally generated city of GenerateCity
+type CityOptions struct {
+	// Seed makes sure the same options always generate the same city
+	Seed int64
+
+	Width, Height float32
+	// BlockSize is the distance between streets in the densest districts
+	BlockSize float32
+
+	// Districts is the number of districts, each of which is either dense, normal or sparse
+	Districts int
+	// ArterialEvery is the number of blocks between arterial roads
+	ArterialEvery int
+	// Curviness is how much the roads are bent, relative to the BlockSize
+	Curviness float32
+	// DeadEnds is the chance a residential street is cut, creating dead ends
+	DeadEnds float32
+
+	River   bool
+	Bridges int
+
+	RingRoad bool
+}
+
+// DefaultCityOptions returns options for a city of 2000 by 2000, with streets 50 apart in its densest districts
+func DefaultCityOptions(seed int64) CityOptions {
+	return CityOptions{
+		Seed:          seed,
+		Width:         2000,
+		Height:        2000,
+		BlockSize:     50,
+		Districts:     6,
+		ArterialEvery: 6,
+		Curviness:     0.8,
+		DeadEnds:      0.08,
+		River:         true,
+		Bridges:       3,
+		RingRoad:      true,
+	}
+}
+
+type cityEdge struct {
+	A, B    int
+	Class   RoadClass
+	Name    string
+	Numbers HouseNumbers
+}
+
+// avenueNames are used for the streets from top to bottom, and numbered streets for those from left to right
+var (
+	avenueNames = []string{
+		"Elm", "Oak", "Maple", "Cedar", "Pine", "Birch", "Willow", "Ash", "Chestnut", "Walnut", "Poplar", "Spruce",
+	}
+	avenueSuffixes = []string{"Avenue", "Lane", "Drive"}
+)
+
+// streetName returns the name of the line'th street, going either from left to right or from top to bottom
+func streetName(line int, horizontal bool) string {
+	if horizontal {
+		suffix := "th"
+		switch {
+		case (line+1)%100 >= 11 && (line+1)%100 <= 13:
+		case (line+1)%10 == 1:
+			suffix = "st"
+		case (line+1)%10 == 2:
+			suffix = "nd"
+		case (line+1)%10 == 3:
+			suffix = "rd"
+		}
+		return fmt.Sprintf("%d%s Street", line+1, suffix)
+	}
+
+	name := avenueNames[line%len(avenueNames)]
+	if round := line / len(avenueNames); round > 0 {
+		name = fmt.Sprintf("%s %s", []string{"North", "South", "East", "West"}[(round-1)%4], name)
+	}
+	return name + " " + avenueSuffixes[line%len(avenueSuffixes)]
+}
+
+// blockNumbers returns the house numbers of the block'th block of a street, which are a hundred per block
+func blockNumbers(block int) HouseNumbers {
+	return HouseNumbers{100*block + 1, 100*block + 99}
+}
+
+// cityGenerator keeps the state while generating a city, using indices into nodes for edges
+type cityGenerator struct {
+	opts  CityOptions
+	rng   *rand.Rand
+	nodes []engo.Point
+	edges []cityEdge
+
+	riverX         func(y float32) float32
+	riverWidth     float32
+	cols, rows     int
+	districtStride []int
+	districtCenter []engo.Point
+}
+
+// GenerateCity generates a city with arterial roads and side streets, districts of varying density, dead ends,
+// and optionally a river with a few bridges and a ring road around the center. The map still needs to be
+// initialized before use.
+func GenerateCity(opts CityOptions) *Map {
+	g := &cityGenerator{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)),
+	}
+	if g.opts.BlockSize <= 0 {
+		g.opts.BlockSize = 50
+	}
+	if g.opts.ArterialEvery <= 0 {
+		g.opts.ArterialEvery = 6
+	}
+
+	g.createDistricts()
+	g.createStreets()
+	if opts.River {
+		g.createRiver()
+	}
+	if opts.RingRoad {
+		g.createRingRoad()
+	}
+	g.createDeadEnds()
+
+	m := g.build()
+	m.Name = fmt.Sprintf("GeneratedCity-%d", opts.Seed)
+	m.Zones = g.zones()
+	m.POIs = g.pois(m)
+	return m
+}
+
+// pois places a station in every precinct, and the other points of interest around the city. They are put at the
+// nodes closest to where they should be, so they are always on the road.
+func (g *cityGenerator) pois(m *Map) []*POI {
+	if len(m.Nodes) == 0 {
+		return nil
+	}
+	at := func(x, y float32) engo.Point {
+		p := engo.Point{X: x * g.opts.Width, Y: y * g.opts.Height}
+		nearest := m.Nodes[0]
+		for _, node := range m.Nodes {
+			if node.Location.PointDistanceSquared(p) < nearest.Location.PointDistanceSquared(p) {
+				nearest = node
+			}
+		}
+		return nearest.Location
+	}
+
+	return []*POI{
+		{Name: "North-West Station", Kind: POIStation, Location: at(0.25, 0.25)},
+		{Name: "North-East Station", Kind: POIStation, Location: at(0.75, 0.25)},
+		{Name: "South-West Station", Kind: POIStation, Location: at(0.25, 0.75)},
+		{Name: "South-East Station", Kind: POIStation, Location: at(0.75, 0.75)},
+		{Name: "City Jail", Kind: POIJail, Location: at(0.35, 0.65)},
+		{Name: "General Hospital", Kind: POIHospital, Location: at(0.65, 0.35)},
+		{Name: "Fire Station 1", Kind: POIFireStation, Location: at(0.35, 0.3)},
+		{Name: "Fire Station 2", Kind: POIFireStation, Location: at(0.65, 0.7)},
+		{Name: "City Tow Depot", Kind: POIDepot, Location: at(0.2, 0.5)},
+		{Name: "Central Bank", Kind: POIBank, Location: at(0.45, 0.45)},
+		{Name: "Savings Bank", Kind: POIBank, Location: at(0.55, 0.55)},
+		{Name: "High School", Kind: POISchool, Location: at(0.3, 0.4)},
+		{Name: "Elementary School", Kind: POISchool, Location: at(0.7, 0.6)},
+		{Name: "The Crown", Kind: POIBar, Location: at(0.5, 0.45)},
+		{Name: "Harbor Pub", Kind: POIBar, Location: at(0.45, 0.55)},
+	}
+}
+
+// zones divides the city into four precincts, with a high-crime area downtown
+func (g *cityGenerator) zones() []*Zone {
+	w, h := g.opts.Width, g.opts.Height
+	rect := func(x, y, width, height float32) []engo.Point {
+		return []engo.Point{{X: x, Y: y}, {X: x + width, Y: y}, {X: x + width, Y: y + height}, {X: x, Y: y + height}}
+	}
+
+	var zones []*Zone
+	for i, name := range []string{"North-West", "North-East", "South-West", "South-East"} {
+		x, y := float32(i%2)*w/2, float32(i/2)*h/2
+		zones = append(zones, &Zone{
+			Name: name + " Precinct", Kind: ZonePrecinct, Polygon: rect(x, y, w/2, h/2), IncidentRate: 1,
+		})
+	}
+	return append(zones, &Zone{
+		Name: "Downtown", Kind: ZoneHotspot, Polygon: rect(w*0.4, h*0.4, w*0.2, h*0.2), IncidentRate: 2,
+	})
+}
+
+func (g *cityGenerator) createDistricts() {
+	count := g.opts.Districts
+	if count < 1 {
+		count = 1
+	}
+
+	center := engo.Point{X: g.opts.Width / 2, Y: g.opts.Height / 2}
+	downtown := 0
+	for i := 0; i < count; i++ {
+		p := engo.Point{X: g.rng.Float32() * g.opts.Width, Y: g.rng.Float32() * g.opts.Height}
+		g.districtCenter = append(g.districtCenter, p)
+		// Either dense (every street), normal (every other street) or sparse (every third street)
+		g.districtStride = append(g.districtStride, 1+g.rng.Intn(3))
+		if p.PointDistance(center) < g.districtCenter[downtown].PointDistance(center) {
+			downtown = i
+		}
+	}
+
+	// The district closest to the center is always dense
+	g.districtStride[downtown] = 1
+}
+
+func (g *cityGenerator) district(p engo.Point) int {
+	nearest := 0
+	for i := range g.districtCenter {
+		if p.PointDistanceSquared(g.districtCenter[i]) < p.PointDistanceSquared(g.districtCenter[nearest]) {
+			nearest = i
+		}
+	}
+	return nearest
+}
+
+// bend returns how far the roads at p are moved, which is smooth so roads curve instead of zigzag
+func (g *cityGenerator) bend(p engo.Point, phase, frequency float32) float32 {
+	amplitude := g.opts.Curviness * g.opts.BlockSize
+	return amplitude * (math.Sin(p.X*frequency+phase) + math.Cos(p.Y*frequency*1.3+phase*2)) / 2
+}
+
+func (g *cityGenerator) createStreets() {
+	bs := g.opts.BlockSize
+	g.cols = int(g.opts.Width/bs) + 1
+	g.rows = int(g.opts.Height/bs) + 1
+
+	phaseX, phaseY := g.rng.Float32()*2*math.Pi, g.rng.Float32()*2*math.Pi
+	frequency := 2 * math.Pi / (bs * float32(g.opts.ArterialEvery) * 2)
+	for i := 0; i < g.cols; i++ {
+		for j := 0; j < g.rows; j++ {
+			base := engo.Point{X: float32(i) * bs, Y: float32(j) * bs}
+			jitter := bs * 0.1
+			g.nodes = append(g.nodes, engo.Point{
+				X: base.X + g.bend(base, phaseX, frequency) + (g.rng.Float32()*2-1)*jitter,
+				Y: base.Y + g.bend(base, phaseY, frequency) + (g.rng.Float32()*2-1)*jitter,
+			})
+		}
+	}
+
+	// A street exists if it's an arterial road, or if the district is dense enough
+	street := func(line int, at engo.Point) (RoadClass, bool) {
+		if line%g.opts.ArterialEvery == 0 {
+			return RoadArterial, true
+		}
+		stride := g.districtStride[g.district(at)]
+		switch {
+		case line%stride != 0:
+			return 0, false
+		case stride == 1 && line%2 == 1:
+			// The streets in between in the densest districts are narrow
+			return RoadAlley, true
+		default:
+			return RoadResidential, true
+		}
+	}
+
+	for i := 0; i < g.cols; i++ {
+		for j := 0; j < g.rows; j++ {
+			a := g.latticeIndex(i, j)
+			if i+1 < g.cols {
+				if class, ok := street(j, g.nodes[a]); ok {
+					g.edges = append(g.edges, cityEdge{a, g.latticeIndex(i+1, j), class, streetName(j, true), blockNumbers(i)})
+				}
+			}
+			if j+1 < g.rows {
+				if class, ok := street(i, g.nodes[a]); ok {
+					g.edges = append(g.edges, cityEdge{a, g.latticeIndex(i, j+1), class, streetName(i, false), blockNumbers(j)})
+				}
+			}
+		}
+	}
+}
+
+func (g *cityGenerator) latticeIndex(i, j int) int {
+	return i*g.rows + j
+}
+
+func (g *cityGenerator) riverSide(p engo.Point) bool {
+	return p.X >= g.riverX(p.Y)
+}
+
+// createRiver lets a river flow from top to bottom, removing all roads crossing it except for a few bridges
+func (g *cityGenerator) createRiver() {
+	x := g.opts.Width * (0.3 + 0.4*g.rng.Float32())
+	amplitude := g.opts.Width * 0.08
+	frequency := 2 * math.Pi / (g.opts.Height * (0.5 + g.rng.Float32()))
+	phase := g.rng.Float32() * 2 * math.Pi
+	g.riverX = func(y float32) float32 {
+		return x + amplitude*math.Sin(y*frequency+phase)
+	}
+	g.riverWidth = g.opts.BlockSize * 0.6
+
+	// Move everything out of the water
+	for i := range g.nodes {
+		g.moveOutOfRiver(&g.nodes[i])
+	}
+
+	type crossing struct {
+		edge int
+		y    float32
+	}
+	var crossings []crossing
+	keep := make([]bool, len(g.edges))
+	for i, e := range g.edges {
+		a, b := g.nodes[e.A], g.nodes[e.B]
+		if g.riverSide(a) == g.riverSide(b) {
+			keep[i] = true
+			continue
+		}
+		crossings = append(crossings, crossing{i, (a.Y + b.Y) / 2})
+	}
+
+	// Bridges are preferably arterial roads, spread out along the river
+	var arterials []crossing
+	for _, c := range crossings {
+		if g.edges[c.edge].Class == RoadArterial {
+			arterials = append(arterials, c)
+		}
+	}
+	if len(arterials) >= g.opts.Bridges {
+		crossings = arterials
+	}
+	sort.Slice(crossings, func(i, j int) bool { return crossings[i].y < crossings[j].y })
+
+	if bridges := g.opts.Bridges; bridges > 0 && len(crossings) > 0 {
+		for k := 0; k < bridges; k++ {
+			keep[crossings[(2*k+1)*len(crossings)/(2*bridges)].edge] = true
+		}
+	}
+
+	g.filterEdges(keep)
+}
+
+func (g *cityGenerator) moveOutOfRiver(p *engo.Point) {
+	if g.riverX == nil {
+		return
+	}
+	rx := g.riverX(p.Y)
+	if math.Abs(p.X-rx) >= g.riverWidth/2 {
+		return
+	}
+	if p.X >= rx {
+		p.X = rx + g.riverWidth/2
+	} else {
+		p.X = rx - g.riverWidth/2
+	}
+}
+
+func (g *cityGenerator) filterEdges(keep []bool) {
+	edges := g.edges[:0]
+	for i, e := range g.edges {
+		if keep[i] {
+			edges = append(edges, e)
+		}
+	}
+	g.edges = edges
+}
+
+// createRingRoad adds a highway around the center, with ramps to the closest streets
+func (g *cityGenerator) createRingRoad() {
+	center := engo.Point{X: g.opts.Width / 2, Y: g.opts.Height / 2}
+	radius := math.Min(g.opts.Width, g.opts.Height) * 0.4
+	count := int(2 * math.Pi * radius / (g.opts.BlockSize * 2))
+	if count < 8 {
+		count = 8
+	}
+
+	// Only connect to streets which aren't part of the ring itself
+	connected := make([]bool, len(g.nodes))
+	for _, e := range g.edges {
+		connected[e.A], connected[e.B] = true, true
+	}
+
+	first := len(g.nodes)
+	for k := 0; k < count; k++ {
+		angle := 2 * math.Pi * float32(k) / float32(count)
+		p := engo.Point{X: center.X + radius*math.Cos(angle), Y: center.Y + radius*math.Sin(angle)}
+		g.moveOutOfRiver(&p)
+		g.nodes = append(g.nodes, p)
+
+		next := first + (k+1)%count
+		g.edges = append(g.edges, cityEdge{first + k, next, RoadHighway, "Ring Road", HouseNumbers{}})
+
+		if k%(count/8) != 0 {
+			continue
+		}
+
+		ramp := -1
+		for i := 0; i < first; i++ {
+			if !connected[i] || (g.riverX != nil && g.riverSide(g.nodes[i]) != g.riverSide(p)) {
+				continue
+			}
+			if ramp < 0 || g.nodes[i].PointDistanceSquared(p) < g.nodes[ramp].PointDistanceSquared(p) {
+				ramp = i
+			}
+		}
+		if ramp >= 0 {
+			exit := fmt.Sprintf("Ring Road Exit %d", k/(count/8)+1)
+			g.edges = append(g.edges, cityEdge{first + k, ramp, RoadArterial, exit, HouseNumbers{}})
+		}
+	}
+}
+
+// createDeadEnds cuts some residential streets
+func (g *cityGenerator) createDeadEnds() {
+	keep := make([]bool, len(g.edges))
+	for i, e := range g.edges {
+		keep[i] = e.Class != RoadResidential || g.rng.Float32() >= g.opts.DeadEnds
+	}
+	g.filterEdges(keep)
+}
+
+// build creates the Map, containing only the largest part of the city that's connected
+func (g *cityGenerator) build() *Map {
+	parent := make([]int, len(g.nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	size := make(map[int]int)
+	for _, e := range g.edges {
+		if a, b := find(e.A), find(e.B); a != b {
+			parent[a] = b
+		}
+	}
+	for _, e := range g.edges {
+		size[find(e.A)]++
+	}
+	largest, largestSize := -1, 0
+	for root, s := range size {
+		if s > largestSize || (s == largestSize && root < largest) {
+			largest, largestSize = root, s
+		}
+	}
+
+	m := new(Map)
+	nodes := make([]*RouteNode, len(g.nodes))
+	node := func(i int) *RouteNode {
+		if nodes[i] == nil {
+			nodes[i] = &RouteNode{ID: uint32(len(m.Nodes) + 1), Location: g.nodes[i]}
+			m.Nodes = append(m.Nodes, nodes[i])
+		}
+		return nodes[i]
+	}
+
+	for _, e := range g.edges {
+		if find(e.A) != largest {
+			continue
+		}
+		m.Connect(node(e.A), node(e.B), Road{Class: e.Class, Name: e.Name, Numbers: e.Numbers})
+	}
+	return m
+}
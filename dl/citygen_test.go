@@ -0,0 +1,49 @@
+package dl
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateCity(t *testing.T) {
+	river := DefaultCityOptions(3)
+	river.Bridges = 1
+	plain := DefaultCityOptions(4)
+	plain.River, plain.RingRoad = false, false
+	small := DefaultCityOptions(5)
+	small.Width, small.Height, small.Districts = 600, 400, 2
+
+	for _, opts := range []CityOptions{DefaultCityOptions(1), DefaultCityOptions(2), river, plain, small} {
+		m := GenerateCity(opts)
+		m.prepare()
+		if err := m.Check(); err != nil {
+			t.Fatal(opts.Seed, err)
+		}
+		if len(m.Nodes) < 20 {
+			t.Fatalf("seed %d: only %d nodes", opts.Seed, len(m.Nodes))
+		}
+		if components := m.strongComponents(m.Nodes); len(components) != 1 {
+			t.Fatalf("seed %d: the city is split in %d parts", opts.Seed, len(components))
+		}
+	}
+}
+
+func TestGenerateCitySeed(t *testing.T) {
+	generate := func(seed int64) []byte {
+		// The name contains the seed, so only the city itself is compared
+		m := GenerateCity(DefaultCityOptions(seed))
+		m.Name = ""
+		buf := &bytes.Buffer{}
+		if err := m.WriteYAML(buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	if !bytes.Equal(generate(42), generate(42)) {
+		t.Fatal("the same seed generated different cities")
+	}
+	if bytes.Equal(generate(42), generate(43)) {
+		t.Fatal("different seeds generated the same city")
+	}
+}
@@ -3,6 +3,9 @@ package dl
 import (
 	"image/color"
 	"log"
+	"math/rand"
+	"strings"
+	"time"
 
 	"engo.io/ecs"
 	"engo.io/engo"
@@ -51,60 +54,68 @@ var (
 	police          map[uint64]DispatchSystemPoliceEntity
 	incidents       map[uint64]DispatchSystemIncidentEntity
 	incidentReports map[uint64]DispatchSystemIncidentReportEntity
+	// gameTime is how long the game has been played, which the DispatchSystem keeps track of
+	gameTime time.Duration
 )
 
+// GameTime returns how long the game has been played. Unlike the wall clock, it only advances while playing.
+func GameTime() time.Duration {
+	return gameTime
+}
+
 type DispatchSystem struct {
+	// SearchRadius is how far from their target units search an area
+	SearchRadius float32
+	// SearchTime is how long units search an area, in seconds, unless they find something
+	SearchTime float32
+	// Seed makes units patrol the same way every time, 0 picks a random one
+	Seed int64
+
 	active            uint64
-	submenuTarget     engo.Point
+	hovered           uint64
+	submenuTarget     RoadPosition
 	submenuActive     bool
 	submenuBackground ui.Graphic
 	submenuActions    []*ui.Button
 	mouseTracker      common.MouseComponent
 	wpEntity          ui.Button
+	orders            orderPanel
 }
 
-func (d *DispatchSystem) QueueCommand(c PoliceCommand) {
+// order gives the commands to the selected unit, at the submenuTarget, where the order panel says new orders go
+func (d *DispatchSystem) order(commands ...PoliceCommand) {
 	unit := police[d.active]
-	// create a temporary node for the submenuTarget
-
-	nearest := CurrentMap.NearestNode(d.submenuTarget)
-	if nearest.Temporary {
-		nearest.TemporaryUsers++
-	} else {
-		temp := new(RouteNode)
-		temp.Location = d.submenuTarget
-		temp.ID = NewMapID()
-		temp.Temporary = true
-		temp.TemporaryUsers = 1
-
-		// And also add the second connected City
-		minDistance := float32(math.MaxFloat32)
-		var secondNearest *RouteNode
-		for _, connection := range nearest.ConnectedTo {
-			conn := CurrentMap.Node(connection)
-			if d := conn.Location.PointDistance(d.submenuTarget); d < minDistance {
-				minDistance = d
-				secondNearest = conn
-			}
-		}
-
-		nearest.ConnectedTo = append(nearest.ConnectedTo, temp.ID)
-		secondNearest.ConnectedTo = append(secondNearest.ConnectedTo, temp.ID)
-		temp.ConnectedTo = []uint32{nearest.ID, secondNearest.ID}
-
-		CurrentMap.AddNode(temp)
-		// TODO: clean this up later to prevent (relatively slow) memory leaking
+	if unit.Status == StatusOutOfService {
+		log.Println(unit.Unit.Name, "is out of service")
+		return
 	}
 
-	unit.QueueCommand(c, d.submenuTarget)
+	var orders []Order
+	for _, c := range commands {
+		// Make sure there's a node at the submenuTarget, so we can route to it
+		node := CurrentMap.AcquireNode(d.submenuTarget)
+		orders = append(orders, NewOrder(c, d.submenuTarget.Point, node))
+	}
+	d.orders.place(unit.PoliceComponent, orders...)
 }
 
 func (d *DispatchSystem) New(w *ecs.World) {
 	police = make(map[uint64]DispatchSystemPoliceEntity)
 	incidents = make(map[uint64]DispatchSystemIncidentEntity)
+	gameTime = 0
 
 	engo.Input.RegisterButton(closeButton, engo.Escape)
 
+	if d.SearchRadius == 0 {
+		d.SearchRadius = 300
+	}
+	if d.SearchTime == 0 {
+		d.SearchTime = 60
+	}
+	if d.Seed == 0 {
+		d.Seed = time.Now().UnixNano()
+	}
+
 	d.mouseTracker.Track = true
 	mouseTrackerBasic := ecs.NewBasic()
 
@@ -113,12 +124,32 @@ func (d *DispatchSystem) New(w *ecs.World) {
 		OnClick func(*ui.Button)
 	}{
 		{Name: "Search area", OnClick: func(*ui.Button) {
-			d.QueueCommand(CommandMove)
-			d.QueueCommand(CommandSearchArea)
+			d.order(CommandMove, CommandSearchArea)
 		}},
 		{Name: "Hold watch", OnClick: func(*ui.Button) {
-			d.QueueCommand(CommandMove)
-			d.QueueCommand(CommandLookout)
+			d.order(CommandMove, CommandLookout)
+		}},
+		{Name: "Close road", OnClick: func(*ui.Button) {
+			d.order(CommandMove, CommandTrafficControl)
+		}},
+		{Name: "Pursue", OnClick: func(*ui.Button) {
+			d.order(CommandPursue)
+		}},
+		{Name: "Return to station", OnClick: func(*ui.Button) {
+			d.ReturnToStation()
+		}},
+		{Name: "Patrol zone", OnClick: func(*ui.Button) {
+			// Outside of any zone, the unit keeps patrolling the zone it had
+			if zone := CurrentMap.ZoneAt(d.submenuTarget.Point); zone != nil {
+				police[d.active].Zone = zone
+			}
+			d.order(CommandPatrol)
+		}},
+		{Name: "Out of service", OnClick: func(*ui.Button) {
+			d.setStatus(d.active, StatusOutOfService)
+		}},
+		{Name: "Back in service", OnClick: func(*ui.Button) {
+			d.setStatus(d.active, StatusAvailable)
 		}},
 	}
 
@@ -149,6 +180,7 @@ func (d *DispatchSystem) New(w *ecs.World) {
 		but.OnClick = func(b *ui.Button) {
 			b.OnMouseOut(b) // TODO: verify if we need this?
 			action.OnClick(b)
+			Radio("%s: %s at %s", police[d.active].Unit.Name, strings.ToLower(action.Name), CurrentMap.Address(d.submenuTarget))
 		}
 		but.OnMouseOver = func(b *ui.Button) {
 			b.Graphic.Color = ui.TooltipColorHover
@@ -199,6 +231,7 @@ func (d *DispatchSystem) New(w *ecs.World) {
 	}
 
 	d.hideSubmenu()
+	d.orders.New(w)
 }
 
 func (d *DispatchSystem) hideSubmenu() {
@@ -231,6 +264,8 @@ func (d *DispatchSystem) showSubmenu(pos engo.Point) {
 }
 
 func (d *DispatchSystem) AddPolice(b *ecs.BasicEntity, r *common.RenderComponent, s *common.SpaceComponent, m *common.MouseComponent, p *PoliceComponent) {
+	// Every unit has its own random source, so it patrols the same way no matter in which order units are updated
+	p.patrol = rand.New(rand.NewSource(d.Seed + int64(b.ID())))
 	police[b.ID()] = DispatchSystemPoliceEntity{b, r, s, m, p}
 }
 
@@ -243,20 +278,27 @@ func (d *DispatchSystem) AddIncidentReport(b *ecs.BasicEntity, r *common.RenderC
 }
 
 func (d *DispatchSystem) Remove(b ecs.BasicEntity) {
+	if p, ok := police[b.ID()]; ok {
+		p.CancelOrders()
+	}
 	delete(police, b.ID())
 	delete(incidents, b.ID())
 	delete(incidentReports, b.ID())
 }
 
 func (d *DispatchSystem) Update(dt float32) {
+	gameTime += time.Duration(dt * float32(time.Second))
+
 	// Allow us to select a police unit
 	if d.active == 0 {
 		for id, police := range police {
 			if police.MouseComponent.Enter {
 				police.Color = ui.PoliceColorHover
+				d.hovered = id
 				ui.StartHovering(id)
 			} else if police.MouseComponent.Leave {
-				police.Color = ui.PoliceColor
+				police.Color = police.Status.Color()
+				d.hovered = 0
 				ui.StopHovering(id)
 			}
 			if police.MouseComponent.Clicked {
@@ -272,70 +314,25 @@ func (d *DispatchSystem) Update(dt float32) {
 	// If we've selected a police unit, we can issue commands
 	if d.active > 0 {
 		police := police[d.active]
+		panelUsed := d.orders.Update(police.PoliceComponent)
 
 		if !d.submenuActive {
 
 			// We can issue commands anywhere we want, as long as it's connected to roads.
 			mX, mY := d.mouseTracker.MouseX, d.mouseTracker.MouseY
-			mP := engo.Point{mX, mY}
-			// Check which city is closest, and try to snap to that road
-			nearest := CurrentMap.NearestNode(mP)
-			// Now figure out which of the roads to snap to
-			// Source for this "distance" method, https://stackoverflow.com/a/6853926/3243814
-			distanceFunc := func(point, l1, l2 engo.Point) float32 {
-				A, B := point.X-l1.X, point.Y-l1.Y
-				C, D := l2.X-l1.X, l2.Y-l1.Y
-				dot := A*C + B*D
-				len_sq := math.Pow(C, 2) + math.Pow(D, 2)
-				param := float32(-1)
-				if len_sq != 0 {
-					param = dot / len_sq
-				}
-				var xx, yy float32
-				if param < 0 {
-					xx, yy = l1.X, l1.Y
-				} else if param > 1 {
-					xx, yy = l2.X, l2.Y
-				} else {
-					xx, yy = l1.X+param*C, l1.Y+param*D
-				}
-				dx, dy := point.X-xx, point.Y-yy
-				return math.Sqrt(math.Pow(dx, 2) + math.Pow(dy, 2))
-			}
-
-			minDistance := float32(math.MaxFloat32)
-			var secondNearest *RouteNode
-			for _, connected := range nearest.ConnectedTo {
-				conn := CurrentMap.Node(connected)
-				if d := distanceFunc(mP, nearest.Location, conn.Location); d < minDistance {
-					minDistance = d
-					secondNearest = conn
-				}
-			}
-
-			// Then figure out where on the road we want to snap
-			// distance on road² = distance to node² - distance to road²
-			dNode := nearest.Location.PointDistance(mP)
-			dRoad := minDistance
-			dOnRoad := math.Sqrt(math.Pow(dNode, 2) - math.Pow(dRoad, 2))
-
-			// Now we have to use triangle similarity to figure out where to place it
-			dX, dY := nearest.Location.X-secondNearest.Location.X, nearest.Location.Y-secondNearest.Location.Y
-			dDiag := math.Sqrt(math.Pow(dX, 2) + math.Pow(dY, 2))
-			ratio := dDiag / dOnRoad // note: dDiag should always be larger
-			waypointdx, waypointdy := dX/ratio, dY/ratio
+			snapped := CurrentMap.SnapToRoad(engo.Point{mX, mY})
 			waypoint := engo.Point{
-				X: nearest.Location.X - waypointdx - ui.WaypointSize/2,
-				Y: nearest.Location.Y - waypointdy - ui.WaypointSize/2,
+				X: snapped.Point.X - ui.WaypointSize/2,
+				Y: snapped.Point.Y - ui.WaypointSize/2,
 			}
 
 			// If we've snapped, we should create some kind of "waypoint-icon" player can click
 			d.wpEntity.Graphic.SpaceComponent.Position = waypoint
 
 			// Player can click, and will open submenu
-			if d.wpEntity.MouseComponent.Clicked {
+			if d.wpEntity.MouseComponent.Clicked && !panelUsed {
 				// Using raw location because it's a HUD
-				d.submenuTarget = waypoint
+				d.submenuTarget = snapped
 				d.showSubmenu(engo.Point{engo.Input.Mouse.X, engo.Input.Mouse.Y})
 			}
 		}
@@ -359,10 +356,11 @@ func (d *DispatchSystem) Update(dt float32) {
 
 		// Allow for cancel behavior
 		if engo.Input.Button(closeButton).JustPressed() || police.MouseComponent.Clicked || submenuUsed {
-			police.Color = ui.PoliceColor
+			police.Color = police.Status.Color()
 			d.active = 0
 			ui.StopHovering(police.ID())
 			d.wpEntity.Graphic.Hidden = true
+			d.orders.hide()
 			if d.submenuActive {
 				d.hideSubmenu()
 			}
@@ -370,64 +368,324 @@ func (d *DispatchSystem) Update(dt float32) {
 	}
 
 	// Process all commands given to any units
-	for _, p := range police {
-		if p.CurrentCommand == CommandHold {
-			p.CurrentCommand, p.CurrentTarget = p.processCommand()
+	for id, p := range police {
+		if p.Current.Command == CommandHold {
+			p.Current = p.nextOrder()
 		}
-		switch p.CurrentCommand {
+		switch p.Current.Command {
 		case CommandHold:
 		// Do nothing
 		case CommandMove:
-			if len(p.CurrentRoute.Nodes) < 1 {
-				p.CurrentRoute = SetRoute(*p.Location, p.CurrentTarget, func(curr, goal, pos *RouteNode) float32 {
-					dx := pos.Location.X - goal.Location.X
-					dy := pos.Location.Y - goal.Location.Y
-					dx2 := pos.Location.X - curr.Location.X
-					dy2 := pos.Location.Y - curr.Location.Y
-					return dx*dx + dy*dy + (dx2*dx2 + dy2*dy2)
-				})
+			d.Drive(p.PoliceComponent, dt)
+		case CommandTransport:
+			// The suspects are dropped off once the unit arrives at the jail
+			if d.Drive(p.PoliceComponent, dt) && p.Current.Command == CommandHold {
+				Radio("%s: %d suspect(s) dropped off at %s", p.Unit.Name, p.Arrested, CurrentMap.AddressAt(p.Current.Target))
+				p.Arrested = 0
+				// Otherwise it would stay transporting until it drives somewhere, and couldn't go on scene
+				d.setStatus(id, StatusAvailable)
 			}
-			p.Move(dt)
 		case CommandLookout:
 			// If there's more to do, stop doing this and go do that other thing
-			if len(p.Commands) > 0 {
-				p.CurrentCommand = CommandHold
+			if p.givesWay() {
+				p.Current.Command = CommandHold
 			}
-			d.Lookout(p.PoliceComponent, p.CurrentTarget)
+			d.Lookout(p.PoliceComponent, p.Current.Target)
 		case CommandSearchArea:
 			// If there's more to do, stop doing this and go do that other thing
-			if len(p.Commands) > 0 {
-				p.CurrentCommand = CommandHold
+			if p.givesWay() {
+				p.Current.Command = CommandHold
+				// The route leads to wherever the unit was searching
+				p.CurrentRoute = Route{}
+				break
+			}
+			if !d.Search(p.PoliceComponent, dt) || d.Lookout(p.PoliceComponent, *p.Location) {
+				p.Current.Command = CommandHold
+				p.CurrentRoute = Route{}
 			}
-			p.Wander(dt, p.CurrentTarget)
-			d.Lookout(p.PoliceComponent, p.CurrentTarget)
 		case CommandTrafficControl:
 			// If there's more to do, stop doing this and go do that other thing
-			if len(p.Commands) > 0 {
-				p.CurrentCommand = CommandHold
+			if p.givesWay() {
+				p.Current.Command = CommandHold
+			} else if p.closure == nil && p.Current.Node != nil {
+				p.closure = CurrentMap.CloseRoads(CurrentMap.IntersectionRoads(p.Current.Node)...)
+			}
+		case CommandPursue:
+			// If there's more to do, stop doing this and go do that other thing
+			if p.givesWay() {
+				p.Current.Command = CommandHold
+				break
+			}
+			if !d.Pursue(p.PoliceComponent, dt) {
+				p.Current.Command = CommandHold
+				break
+			}
+			d.Lookout(p.PoliceComponent, *p.Location)
+		case CommandPatrol:
+			// If there's more to do, stop doing this and go do that other thing
+			if p.givesWay() {
+				p.Current.Command = CommandHold
+				// The route leads to wherever the unit was patrolling to
+				p.CurrentRoute = Route{}
+				break
 			}
+			if !d.Patrol(p.PoliceComponent, dt) {
+				p.Current.Command = CommandHold
+				break
+			}
+			d.Lookout(p.PoliceComponent, *p.Location)
 		default:
-			log.Println("Dunno what to do", p.CurrentCommand)
+			log.Println("Dunno what to do", p.Current.Command)
 		}
 
 		if p.CurrentResolve.BasicEntity != nil {
+			if suspects, ok := p.CurrentResolve.Incident.(Arrestable); ok && p.Unit.Capabilities.Has(CapabilityArrest) {
+				d.Arrest(p.PoliceComponent, suspects.Suspects())
+			}
 			engo.Mailbox.Dispatch(IncidentResolveMessage{p.CurrentResolve.IncidentComponent, p.CurrentResolve.BasicEntity})
 			p.CurrentResolve = DispatchSystemIncidentEntity{}
 		}
+
+		// Units which are out of service stay that way until they're put back in service
+		if p.Status != StatusOutOfService {
+			d.setStatus(id, p.commandStatus())
+		}
 	}
 }
 
-func (d *DispatchSystem) Lookout(p *PoliceComponent, t engo.Point) {
+// setStatus changes the status of the unit, and its color if it isn't selected or hovered over
+func (d *DispatchSystem) setStatus(id uint64, status UnitStatus) {
+	p := police[id]
+	if err := p.SetStatus(status); err != nil {
+		log.Println("Unable to change status:", err)
+		return
+	}
+	if id != d.active && id != d.hovered {
+		p.Color = p.Status.Color()
+	}
+}
+
+// Drive moves the unit towards its target, planning the route there first if needed. It returns false if the target
+// can't be reached.
+func (d *DispatchSystem) Drive(p *PoliceComponent, dt float32) bool {
+	cost, h := TravelTimeCost(p.Unit.Speed), TravelTimeHeuristic(p.Unit.Speed)
+	if len(p.CurrentRoute.Nodes) < 1 {
+		route, err := SetRoute(*p.Location, p.Current.Target, cost, h)
+		if err != nil {
+			log.Println("Unable to move:", err)
+			p.Current.Command = CommandHold
+			return false
+		}
+		p.CurrentRoute = route
+	} else if CurrentMap.NeedsReplanning(&p.CurrentRoute) {
+		route, err := CurrentMap.Replan(p.CurrentRoute, cost, h)
+		if err != nil {
+			log.Println("Unable to move:", err)
+			p.Current.Command = CommandHold
+			p.CurrentRoute = Route{}
+			return false
+		}
+		p.CurrentRoute = route
+	}
+	p.Move(dt)
+	return true
+}
+
+// Arrest takes the suspects into the unit, as far as there's room for them, and has it bring them to the nearest jail
+func (d *DispatchSystem) Arrest(p *PoliceComponent, suspects int) {
+	if room := p.Unit.PassengersCuffed - p.Arrested; suspects > room {
+		Radio("%s: no room for %d suspect(s)", p.Unit.Name, suspects-room)
+		suspects = room
+	}
+	if suspects <= 0 {
+		return
+	}
+	p.Arrested += suspects
+
+	jail := CurrentMap.NearestPOI(CurrentMap.SnapToRoad(*p.Location), POIJail, p.Unit.Speed)
+	if jail == nil {
+		log.Println("No jail to bring the suspects to")
+		return
+	}
+	pos := CurrentMap.Entrance(jail)
+	p.QueueOrder(NewOrder(CommandTransport, pos.Point, CurrentMap.AcquireNode(pos)))
+	Radio("%s: bringing %d suspect(s) to %s", p.Unit.Name, p.Arrested, jail)
+}
+
+// ReturnToStation sends the selected unit back to its station, or to the nearest base of its agency if it has none
+func (d *DispatchSystem) ReturnToStation() {
+	unit := police[d.active]
+	station := unit.Station
+	if kind, ok := unit.Unit.Agency.Base(); ok && station == nil {
+		station = CurrentMap.NearestPOI(CurrentMap.SnapToRoad(*unit.Location), kind, unit.Unit.Speed)
+	}
+	if station == nil {
+		log.Println("No station to return to")
+		return
+	}
+	d.submenuTarget = CurrentMap.Entrance(station)
+	d.order(CommandMove)
+}
+
+// Pursue moves the unit towards the incident closest to its target, following it wherever it goes. It returns false
+// once there is nothing (left) to pursue.
+func (d *DispatchSystem) Pursue(p *PoliceComponent, dt float32) bool {
+	if p.pursued.BasicEntity == nil {
+		var closest float32 = math.MaxFloat32
+		for _, incident := range incidents {
+			if dist := incident.Location.PointDistance(p.Current.Target); dist < closest {
+				p.pursued, closest = incident, dist
+			}
+		}
+		if p.pursued.BasicEntity == nil {
+			log.Println("Nothing to pursue")
+			return false
+		}
+		p.pursuit = NewPlanner(CurrentMap, TravelTimeCost(p.Unit.Speed), TravelTimeHeuristic(p.Unit.Speed))
+	}
+	if _, ok := incidents[p.pursued.ID()]; !ok {
+		return false
+	}
+
+	// Keep driving to the node the unit is already heading to
+	start := CurrentMap.NearestNode(*p.Location)
+	if len(p.CurrentRoute.Nodes) > 0 && CurrentMap.Node(p.CurrentRoute.Nodes[0].ID) == p.CurrentRoute.Nodes[0] {
+		start = p.CurrentRoute.Nodes[0]
+	}
+	route, err := p.pursuit.Route(start, CurrentMap.NearestNode(*p.pursued.Location))
+	if err != nil {
+		log.Println("Unable to pursue:", err)
+		return false
+	}
+	if len(p.CurrentRoute.Nodes) > 0 && start == p.CurrentRoute.Nodes[0] {
+		route.Roads[0] = p.CurrentRoute.Roads[0]
+	}
+	p.CurrentRoute = route
+	p.Move(dt)
+
+	// Move stops once the route has been driven, but the incident may move on
+	p.Current.Command = CommandPursue
+	return true
+}
+
+// Patrol drives the unit around the zone it's assigned to, from one random node in the zone to another. It returns
+// false if the unit has no zone to patrol.
+func (d *DispatchSystem) Patrol(p *PoliceComponent, dt float32) bool {
+	if p.Zone == nil {
+		log.Println("No zone to patrol")
+		return false
+	}
+
+	if len(p.CurrentRoute.Nodes) == 0 || CurrentMap.NeedsReplanning(&p.CurrentRoute) {
+		nodes := CurrentMap.NodesIn(p.Zone)
+		if len(nodes) == 0 {
+			log.Println("No roads to patrol in", p.Zone.Name)
+			return false
+		}
+		target := nodes[p.patrol.Intn(len(nodes))]
+		route, err := SetRoute(*p.Location, target.Location, TravelTimeCost(p.Unit.Speed), TravelTimeHeuristic(p.Unit.Speed))
+		if err != nil {
+			log.Println("Unable to patrol:", err)
+			return false
+		}
+		p.CurrentRoute = route
+	}
+	p.Move(dt)
+
+	// Move stops once the route has been driven, but the patrol goes on
+	p.Current.Command = CommandPatrol
+	return true
+}
+
+// Lookout looks for incidents the unit can help with, and returns true if it sees one. Once all units an incident
+// requires are there, one of them resolves it; the one that can arrest the suspects, if there are any.
+func (d *DispatchSystem) Lookout(p *PoliceComponent, t engo.Point) bool {
 	maxDist := p.Unit.ViewDistance
+	var found bool
 
 	if p.CurrentResolve.BasicEntity == nil {
 		// Find new target, if any
-		for _, incident := range incidents {
-			if incident.Location.PointDistance(*p.Location) < maxDist {
-				p.CurrentResolve = incident
-				log.Println("Set currentResolve")
-				break
+		for id, incident := range incidents {
+			if incident.Location.PointDistance(*p.Location) >= maxDist || !p.Unit.Capabilities.Helps(incident.Incident) {
+				continue
+			}
+			found = true
+
+			scene, resolving := unitsAt(incident)
+			if resolving {
+				continue
+			}
+			if missing := MissingCapabilities(incident.Incident, scene); len(missing) > 0 {
+				if p.scene != id {
+					p.scene = id
+					Radio("%s: on scene at %s, waiting for %s", p.Unit.Name, CurrentMap.AddressAt(*incident.Location), missing)
+				}
+				continue
+			}
+
+			resolver := p
+			if _, ok := incident.Incident.(Arrestable); ok {
+				for _, unit := range scene {
+					if unit.Unit.Capabilities.Has(CapabilityArrest) {
+						resolver = unit
+						break
+					}
+				}
 			}
+			resolver.CurrentResolve = incident
+			log.Println("Set currentResolve")
+			break
+		}
+	}
+	return found
+}
+
+// Search drives the unit around the roads near its target, searching the ones it searched the longest ago first. It
+// returns false once the time to search is up.
+func (d *DispatchSystem) Search(p *PoliceComponent, dt float32) bool {
+	p.searchTime += dt
+	if p.searchTime > d.SearchTime {
+		Radio("%s: searched %s, nothing found", p.Unit.Name, CurrentMap.AddressAt(p.Current.Target))
+		return false
+	}
+
+	if len(p.CurrentRoute.Nodes) == 0 || CurrentMap.NeedsReplanning(&p.CurrentRoute) {
+		route, err := CurrentMap.SearchRoute(CurrentMap.SnapToRoad(*p.Location), p.Current.Target, d.SearchRadius,
+			p.Unit.Speed, p.searched, gameTime)
+		if err != nil {
+			log.Println("Unable to search:", err)
+			return false
+		}
+		p.CurrentRoute = route
+	}
+
+	node, road := p.CurrentRoute.Nodes[0], p.CurrentRoute.Roads[0]
+	p.Move(dt)
+	if road != nil && (len(p.CurrentRoute.Nodes) == 0 || p.CurrentRoute.Nodes[0] != node) {
+		if p.searched == nil {
+			p.searched = make(map[*Road]time.Duration)
+		}
+		p.searched[road] = gameTime
+		if back := reverseRoad(CurrentMap, node, road); back != nil {
+			p.searched[back] = gameTime
+		}
+	}
+
+	// Move stops once the route has been driven, but the search goes on
+	p.Current.Command = CommandSearchArea
+	return true
+}
+
+// unitsAt returns the units which can see the incident, and whether one of them is already resolving it
+func unitsAt(incident DispatchSystemIncidentEntity) ([]*PoliceComponent, bool) {
+	var units []*PoliceComponent
+	for _, unit := range police {
+		if unit.CurrentResolve.BasicEntity == incident.BasicEntity {
+			return nil, true
+		}
+		if incident.Location.PointDistance(*unit.Location) < unit.Unit.ViewDistance {
+			units = append(units, unit.PoliceComponent)
 		}
 	}
+	return units, false
 }
@@ -0,0 +1,67 @@
+package dl
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+)
+
+func TestDropOffThenHoldWatch(t *testing.T) {
+	defer func(m *Map, p map[uint64]DispatchSystemPoliceEntity, i map[uint64]DispatchSystemIncidentEntity,
+		at time.Duration) {
+		CurrentMap, police, incidents, gameTime = m, p, i, at
+	}(CurrentMap, police, incidents, gameTime)
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	CurrentMap = RandomMap(5, 5, 100, 100)
+	CurrentMap.Initialize()
+	police = make(map[uint64]DispatchSystemPoliceEntity)
+	incidents = make(map[uint64]DispatchSystemIncidentEntity)
+
+	location := engo.Point{X: 100, Y: 100}
+	unit := &PoliceComponent{
+		Location: &location,
+		Unit:     UnitType{Name: "Car", Capabilities: Capabilities{CapabilityArrest}, Speed: 100, ViewDistance: 50},
+		Arrested: 2,
+	}
+	police[1] = DispatchSystemPoliceEntity{&ecs.BasicEntity{}, &common.RenderComponent{}, &common.SpaceComponent{},
+		&common.MouseComponent{}, unit}
+
+	// The suspects are brought to jail, after which the unit holds watch right there
+	jail := CurrentMap.SnapToRoad(engo.Point{X: 300, Y: 300})
+	unit.QueueOrder(NewOrder(CommandTransport, jail.Point, CurrentMap.AcquireNode(jail)))
+	unit.QueueOrder(NewOrder(CommandMove, jail.Point, CurrentMap.AcquireNode(jail)))
+	unit.QueueOrder(NewOrder(CommandLookout, jail.Point, CurrentMap.AcquireNode(jail)))
+
+	d := &DispatchSystem{}
+	for i := 0; i < 1000 && unit.Current.Command != CommandLookout; i++ {
+		d.Update(0.1)
+	}
+	d.Update(0.1)
+
+	if unit.Current.Command != CommandLookout || unit.Arrested != 0 {
+		t.Fatalf("the unit is at %v with %d suspects, doing %v", location, unit.Arrested, unit.Current.Command)
+	}
+	if unit.Status != StatusOnScene {
+		t.Fatalf("the unit is %s while holding watch", unit.Status)
+	}
+	var statuses []UnitStatus
+	for _, change := range unit.StatusHistory {
+		statuses = append(statuses, change.To)
+	}
+	if len(statuses) < 2 || statuses[0] != StatusTransporting || statuses[1] != StatusAvailable {
+		t.Fatalf("the unit went through %v, expected to become available once the suspects were dropped off", statuses)
+	}
+	if strings.Contains(logged.String(), "Unable to change status") {
+		t.Fatalf("a status change was rejected:\n%s", logged.String())
+	}
+}
@@ -6,10 +6,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 
 	"engo.io/engo"
-	"github.com/luxengine/math"
-	yaml "gopkg.in/yaml.v2"
 )
 
 var CurrentMap *Map
@@ -17,20 +16,193 @@ var CurrentMap *Map
 type Map struct {
 	Name     string
 	Nodes    []*RouteNode
+	Zones    []*Zone
+	POIs     []*POI
 	nodesMap map[uint32]*RouteNode
+	index    *spatialIndex
+	streets  *streetIndex
+	version  uint64
+	// changes are the latest changes to roads, in the order they were made. Those up to and including version
+	// forgotten aren't known anymore.
+	changes   []RoadChange
+	forgotten uint64
+	// trafficVersion changes separately, as changes in traffic only affect the cost of driving on the roads
+	trafficVersion uint64
 }
 
+// Initialize prepares the map for use, and makes it the CurrentMap
 func (m *Map) Initialize() {
+	m.prepare()
+	CurrentMap = m
+}
+
+// prepare completes the roads of all nodes, and indexes them
+func (m *Map) prepare() {
 	m.nodesMap = make(map[uint32]*RouteNode)
 	for _, node := range m.Nodes {
 		m.nodesMap[node.ID] = node
+		// Make sure NewMapID never returns an ID that's already in use
+		if node.ID > counter {
+			counter = node.ID
+		}
+	}
+	for _, node := range m.Nodes {
+		m.initializeRoads(node)
+	}
+	for _, node := range m.Nodes {
+		m.addReverseRoads(node)
+	}
+	m.buildIndex()
+	m.version++
+	m.changes, m.forgotten = nil, m.version
+}
+
+// Version changes every time nodes or roads are added, removed or moved, so anything derived from the map knows
+// when to update
+func (m *Map) Version() uint64 {
+	return m.version
+}
+
+// maxRoadChanges is the number of changes to roads the map remembers. Anything which doesn't keep up with the map
+// for longer than that has to start over.
+const maxRoadChanges = 1 << 14
+
+// RoadChange is a road which was added to the map, removed from it, moved, or closed, slowed down or reopened. For
+// the latter, the road itself stays where it is and From and To are nil.
+type RoadChange struct {
+	RoadSegment
+	version uint64
+}
+
+// ChangedRoads returns the changes to roads made since the given version of the map, in the order they were made. If
+// the map doesn't remember changes that far back, like before it was prepared, false is returned and everything
+// derived from the map has to be computed again.
+func (m *Map) ChangedRoads(since uint64) ([]RoadChange, bool) {
+	if since < m.forgotten {
+		return nil, false
+	}
+	first := sort.Search(len(m.changes), func(i int) bool { return m.changes[i].version > since })
+	return m.changes[first:], true
+}
+
+// HasRoad indicates whether the road of the segment is still on the map, between the same nodes
+func (m *Map) HasRoad(seg RoadSegment) bool {
+	return seg.From != nil && seg.To != nil && m.Node(seg.From.ID) == seg.From && m.Node(seg.To.ID) == seg.To &&
+		seg.From.Road(seg.To.ID) == seg.Road
+}
+
+// roadChanged records a change to the road in the current version of the map. The nodes are nil if only its
+// restrictions changed.
+func (m *Map) roadChanged(from, to *RouteNode, road *Road) {
+	road.changed = m.version
+	if len(m.changes) >= maxRoadChanges {
+		half := len(m.changes) / 2
+		m.forgotten = m.changes[half-1].version
+		m.changes = append(m.changes[:0], m.changes[half:]...)
+	}
+	m.changes = append(m.changes, RoadChange{RoadSegment{from, to, road}, m.version})
+}
+
+// TrafficVersion changes every time the traffic on any of the roads changes noticeably. Traffic doesn't change the
+// version of the map: routes planned before aren't planned again because of it.
+func (m *Map) TrafficVersion() uint64 {
+	return m.trafficVersion
+}
+
+func (m *Map) buildIndex() {
+	m.index = newSpatialIndex(m.Nodes)
+	for _, node := range m.Nodes {
+		m.index.addNode(node)
+	}
+	for _, node := range m.Nodes {
+		for _, road := range node.Roads {
+			if to := m.Node(road.To); to != nil {
+				m.index.addSegment(RoadSegment{node, to, road})
+			}
+		}
+	}
+}
+
+// spatial returns the spatial index, building it if the map hasn't been initialized yet
+func (m *Map) spatial() *spatialIndex {
+	if m.index == nil {
+		m.buildIndex()
+	}
+	return m.index
+}
+
+// initializeRoads makes sure every entry in ConnectedTo has a Road and vice versa, and computes missing lengths
+func (m *Map) initializeRoads(n *RouteNode) {
+	for _, id := range n.ConnectedTo {
+		if n.Road(id) == nil {
+			n.Roads = append(n.Roads, &Road{To: id})
+		}
+	}
+	for _, road := range n.Roads {
+		if !n.IsConnectedTo(road.To) {
+			n.ConnectedTo = append(n.ConnectedTo, road.To)
+		}
+		if road.Length <= 0 {
+			if to := m.Node(road.To); to != nil {
+				road.Length = n.Location.PointDistance(to.Location)
+			}
+		}
+	}
+}
+
+// addReverseRoads adds the road in the opposite direction for every two-way road of the node, if it's missing
+func (m *Map) addReverseRoads(n *RouteNode) {
+	for _, road := range n.Roads {
+		if road.OneWay {
+			continue
+		}
+		to := m.Node(road.To)
+		if to == nil || to == n || to.Road(n.ID) != nil {
+			continue
+		}
+
+		reverse := *road
+		reverse.To, reverse.Numbers = n.ID, road.Numbers.reversed()
+		to.Roads = append(to.Roads, &reverse)
+		to.ConnectedTo = append(to.ConnectedTo, n.ID)
 	}
-	CurrentMap = m
 }
 
 func (m *Map) AddNode(n *RouteNode) {
+	m.version++
 	m.Nodes = append(m.Nodes, n)
 	m.nodesMap[n.ID] = n
+	if m.index != nil {
+		m.index.addNode(n)
+	}
+}
+
+// Connect adds a road from one node to another. Unless the road is one-way, the reverse road is added as well.
+func (m *Map) Connect(from, to *RouteNode, road Road) {
+	road.To = to.ID
+	if road.Length <= 0 {
+		road.Length = from.Location.PointDistance(to.Location)
+	}
+	if from.Road(to.ID) == nil {
+		r := road
+		m.addRoad(from, to, &r)
+	}
+
+	if !road.OneWay && to.Road(from.ID) == nil {
+		reverse := road
+		reverse.To, reverse.Numbers = from.ID, road.Numbers.reversed()
+		m.addRoad(to, from, &reverse)
+	}
+}
+
+func (m *Map) addRoad(from, to *RouteNode, road *Road) {
+	m.version++
+	m.roadChanged(from, to, road)
+	from.Roads = append(from.Roads, road)
+	from.ConnectedTo = append(from.ConnectedTo, to.ID)
+	if m.index != nil {
+		m.index.addSegment(RoadSegment{from, to, road})
+	}
 }
 
 var counter uint32 = 50000
@@ -44,6 +216,19 @@ func RandomMap(w, h uint32, width, height float32) *Map {
 	m := new(Map)
 	m.Name = "RandomMap"
 	m.Nodes = make([]*RouteNode, w*h)
+
+	// The outer edge is a ring road, and every third street is an arterial road
+	lineClass := func(k, n uint32) RoadClass {
+		switch {
+		case k == 0 || k+1 == n:
+			return RoadHighway
+		case k%3 == 0:
+			return RoadArterial
+		default:
+			return RoadResidential
+		}
+	}
+
 	for i := uint32(0); i < w; i++ {
 		for j := uint32(0); j < h; j++ {
 			rn := new(RouteNode)
@@ -54,17 +239,20 @@ func RandomMap(w, h uint32, width, height float32) *Map {
 			}
 			if j > 0 {
 				// Connect to every node on the left
-				rn.ConnectedTo = append(rn.ConnectedTo, rn.ID-1)
+				rn.Roads = append(rn.Roads, &Road{To: rn.ID - 1, Class: lineClass(i, w)})
 			}
 			if j+1 < h {
-				rn.ConnectedTo = append(rn.ConnectedTo, rn.ID+1)
+				rn.Roads = append(rn.Roads, &Road{To: rn.ID + 1, Class: lineClass(i, w)})
 			}
 			if i > 0 {
 				// Connect to every node on the top
-				rn.ConnectedTo = append(rn.ConnectedTo, rn.ID-h)
+				rn.Roads = append(rn.Roads, &Road{To: rn.ID - h, Class: lineClass(j, h)})
 			}
 			if i+1 < w {
-				rn.ConnectedTo = append(rn.ConnectedTo, rn.ID+h)
+				rn.Roads = append(rn.Roads, &Road{To: rn.ID + h, Class: lineClass(j, h)})
+			}
+			for _, road := range rn.Roads {
+				rn.ConnectedTo = append(rn.ConnectedTo, road.To)
 			}
 			m.Nodes[i*h+j] = rn
 		}
@@ -80,16 +268,90 @@ func (m *Map) Node(id uint32) *RouteNode {
 	return n
 }
 
+// NearestNode returns the node closest to the origin, or nil if the map is empty
 func (m *Map) NearestNode(origin engo.Point) *RouteNode {
-	var maxDistance float32 = math.MaxFloat32
-	var nearestNode uint32
+	nodes := m.spatial().nearestNodes(origin, 1)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// NearestNodes returns at most k nodes closest to the origin, ordered by distance
+func (m *Map) NearestNodes(origin engo.Point, k int) []*RouteNode {
+	return m.spatial().nearestNodes(origin, k)
+}
+
+// NodesWithin returns all nodes within the given radius of the origin, in no particular order
+func (m *Map) NodesWithin(origin engo.Point, radius float32) []*RouteNode {
+	return m.spatial().nodesWithin(origin, radius)
+}
+
+// NearestRoad returns the road segment closest to the origin, and false if the map has no roads
+func (m *Map) NearestRoad(origin engo.Point) (RoadSegment, bool) {
+	seg, _, ok := m.spatial().nearestSegment(origin)
+	return seg, ok
+}
+
+// Check verifies the internal consistency of an initialized map, which is useful after modifying it a lot
+func (m *Map) Check() error {
+	var errs MapErrors
+
+	if len(m.nodesMap) != len(m.Nodes) {
+		errs.add(0, "%d nodes, but %d node IDs", len(m.Nodes), len(m.nodesMap))
+	}
+
 	for _, node := range m.Nodes {
-		if d := node.Location.PointDistanceSquared(origin); d < maxDistance {
-			maxDistance = d
-			nearestNode = node.ID
+		if m.nodesMap[node.ID] != node {
+			errs.add(node.ID, "not found by its ID")
+		}
+		if node.Temporary && node.TemporaryUsers == 0 {
+			errs.add(node.ID, "temporary node without users")
+		}
+		if len(node.ConnectedTo) != len(node.Roads) {
+			errs.add(node.ID, "%d connections, but %d roads", len(node.ConnectedTo), len(node.Roads))
+		}
+
+		seen := make(map[uint32]struct{})
+		for _, road := range node.Roads {
+			if _, ok := seen[road.To]; ok {
+				errs.add(node.ID, "multiple roads to node %d", road.To)
+			}
+			seen[road.To] = struct{}{}
+
+			if !node.IsConnectedTo(road.To) {
+				errs.add(node.ID, "road to node %d missing from connections", road.To)
+			}
+			if m.Node(road.To) == nil {
+				errs.add(node.ID, "road to unknown node %d", road.To)
+			}
 		}
 	}
-	return m.Node(nearestNode)
+
+	if m.index != nil {
+		var indexed int
+		for _, nodes := range m.index.nodes {
+			indexed += len(nodes)
+			for _, node := range nodes {
+				if m.Node(node.ID) != node {
+					errs.add(node.ID, "indexed, but not on the map")
+				}
+			}
+		}
+		if indexed != len(m.Nodes) {
+			errs.add(0, "%d nodes, but %d indexed", len(m.Nodes), indexed)
+		}
+
+		for _, segments := range m.index.segments {
+			for _, seg := range segments {
+				if seg.From.Road(seg.To.ID) != seg.Road || m.Node(seg.From.ID) != seg.From {
+					errs.add(seg.From.ID, "indexed road to node %d is not on the map", seg.To.ID)
+				}
+			}
+		}
+	}
+
+	return errs.errorOrNil()
 }
 
 func (m Map) URL() string {
@@ -108,18 +370,43 @@ func (m Map) String() string {
 type RouteNode struct {
 	ID             uint32
 	Location       engo.Point
-	Temporary      bool
-	TemporaryUsers uint8
+	Temporary      bool  `yaml:"-"`
+	TemporaryUsers uint8 `yaml:"-"`
 
 	ConnectedTo []uint32 `yaml:"connectedTo"`
+	Roads       []*Road  `yaml:"roads"`
 }
 
 func (rn RouteNode) String() string {
 	return fmt.Sprintf("Node %d", rn.ID)
 }
 
+// Road returns the road going to the given node, or nil if there is none
+func (rn *RouteNode) Road(to uint32) *Road {
+	for _, road := range rn.Roads {
+		if road.To == to {
+			return road
+		}
+	}
+	return nil
+}
+
+func (rn *RouteNode) IsConnectedTo(id uint32) bool {
+	for _, conn := range rn.ConnectedTo {
+		if conn == id {
+			return true
+		}
+	}
+	return false
+}
+
 type Route struct {
 	Nodes []*RouteNode
+	// Roads contains the road leading to the node at the same index in Nodes; the first one is nil
+	Roads []*Road
+
+	// planned is the version of the map the route was planned on
+	planned uint64
 }
 
 func (r Route) String() string {
@@ -132,6 +419,9 @@ func (r Route) String() string {
 }
 
 type MapLoader struct {
+	// Strict makes fields in the map file which the Map doesn't know about an error
+	Strict bool
+
 	maps map[string]*Map
 }
 
@@ -145,8 +435,10 @@ func (ml *MapLoader) Load(url string, data io.Reader) error {
 		return err
 	}
 
-	mapDefinition := new(Map)
-	yaml.Unmarshal(b, mapDefinition)
+	mapDefinition, err := ParseMap(b, ml.Strict)
+	if err != nil {
+		return fmt.Errorf("invalid map %s:\n%v", url, err)
+	}
 	mapDefinition.Name = url
 	ml.maps[url] = mapDefinition
 
@@ -0,0 +1,54 @@
+package dl
+
+import (
+	"testing"
+
+	"engo.io/engo"
+)
+
+func TestChangedRoads(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	if _, ok := m.ChangedRoads(m.Version() - 1); ok {
+		t.Fatal("remembers changes from before the map was prepared")
+	}
+	if changes, ok := m.ChangedRoads(m.Version()); !ok || len(changes) != 0 {
+		t.Fatal(changes, ok)
+	}
+
+	// Splitting a two-way road removes two roads, and adds four
+	version := m.Version()
+	pos := m.SnapToRoad(engo.Point{150, 100})
+	reverse := pos.To.Road(pos.From.ID)
+	n := m.AcquireNode(pos)
+	changes, ok := m.ChangedRoads(version)
+	if !ok || len(changes) != 6 {
+		t.Fatal(changes, ok)
+	}
+	for _, c := range changes {
+		if removed := c.Road == pos.Road || c.Road == reverse; m.HasRoad(c.RoadSegment) == removed {
+			t.Fatalf("road from %d to %d", c.From.ID, c.To.ID)
+		}
+	}
+
+	// Closing roads only changes the roads themselves
+	version = m.Version()
+	m.CloseRoads(n.Roads...)
+	changes, _ = m.ChangedRoads(version)
+	if len(changes) != 2 || changes[0].From != nil || changes[0].Road != n.Roads[0] {
+		t.Fatal(changes)
+	}
+
+	// Eventually, old changes are forgotten
+	m.ReleaseNode(n)
+	version = m.Version()
+	for i := 0; i < maxRoadChanges/2; i++ {
+		m.ReleaseNode(m.AcquireNode(pos))
+	}
+	if _, ok := m.ChangedRoads(version); ok {
+		t.Fatal("remembers every change")
+	}
+	if changes, ok := m.ChangedRoads(m.Version() - 10); !ok || len(changes) == 0 {
+		t.Fatal("forgot the last changes")
+	}
+}
@@ -0,0 +1,301 @@
+package dl
+
+import (
+	"io"
+
+	"engo.io/engo"
+)
+
+// mapEdit is a change to a map which can be undone
+type mapEdit interface {
+	apply(m *Map)
+	revert(m *Map)
+}
+
+type addNodeEdit struct {
+	node *RouteNode
+}
+
+func (e *addNodeEdit) apply(m *Map)  { m.AddNode(e.node) }
+func (e *addNodeEdit) revert(m *Map) { m.removeNode(e.node) }
+
+type deleteNodeEdit struct {
+	node  *RouteNode
+	roads []RoadSegment
+}
+
+func (e *deleteNodeEdit) apply(m *Map) {
+	e.roads = m.incomingRoads(e.node)
+	for _, road := range e.node.Roads {
+		if to := m.Node(road.To); to != nil {
+			e.roads = append(e.roads, RoadSegment{e.node, to, road})
+		}
+	}
+
+	for _, seg := range e.roads {
+		m.removeRoad(seg.From, seg.Road)
+	}
+	m.removeNode(e.node)
+}
+
+func (e *deleteNodeEdit) revert(m *Map) {
+	m.AddNode(e.node)
+	for _, seg := range e.roads {
+		m.addRoad(seg.From, seg.To, seg.Road)
+	}
+}
+
+// moveNodeEdit moves a node. Moving it recomputes the lengths of its roads, so the lengths from before are
+// restored when it's undone.
+type moveNodeEdit struct {
+	node     *RouteNode
+	from, to engo.Point
+	lengths  map[*Road]float32
+}
+
+func (e *moveNodeEdit) apply(m *Map) { m.moveNode(e.node, e.to) }
+
+func (e *moveNodeEdit) revert(m *Map) {
+	m.moveNode(e.node, e.from)
+	for road, length := range e.lengths {
+		road.Length = length
+	}
+}
+
+// roadsEdit replaces the roads between two nodes, in both directions. A nil road means there is none.
+type roadsEdit struct {
+	a, b          *RouteNode
+	before, after [2]*Road
+}
+
+func (e *roadsEdit) apply(m *Map)  { e.set(m, e.after) }
+func (e *roadsEdit) revert(m *Map) { e.set(m, e.before) }
+
+func (e *roadsEdit) set(m *Map, roads [2]*Road) {
+	m.replaceRoad(e.a, e.b, roads[0])
+	m.replaceRoad(e.b, e.a, roads[1])
+}
+
+// MapEditor changes a map, remembering every change so it can be undone and redone
+type MapEditor struct {
+	Map *Map
+
+	undo, redo []mapEdit
+	saved      mapEdit
+}
+
+// NewMapEditor starts editing the map. Temporary nodes and road restrictions are removed first, as they are not part
+// of the map itself.
+func NewMapEditor(m *Map) *MapEditor {
+	if m.nodesMap == nil {
+		m.prepare()
+	}
+	m.liftAllRestrictions()
+
+	var temporary []*RouteNode
+	for _, node := range m.Nodes {
+		if node.Temporary {
+			temporary = append(temporary, node)
+		}
+	}
+	for _, node := range temporary {
+		m.removeTemporary(node)
+	}
+
+	return &MapEditor{Map: m}
+}
+
+func (e *MapEditor) do(edit mapEdit) {
+	edit.apply(e.Map)
+	e.undo = append(e.undo, edit)
+	e.redo = e.redo[:0]
+}
+
+// Undo reverts the last change, if any
+func (e *MapEditor) Undo() bool {
+	if len(e.undo) == 0 {
+		return false
+	}
+	edit := e.undo[len(e.undo)-1]
+	e.undo = e.undo[:len(e.undo)-1]
+	edit.revert(e.Map)
+	e.redo = append(e.redo, edit)
+	return true
+}
+
+// Redo applies the last change that was undone, if any
+func (e *MapEditor) Redo() bool {
+	if len(e.redo) == 0 {
+		return false
+	}
+	edit := e.redo[len(e.redo)-1]
+	e.redo = e.redo[:len(e.redo)-1]
+	edit.apply(e.Map)
+	e.undo = append(e.undo, edit)
+	return true
+}
+
+// Modified indicates whether or not the map was changed since it was last saved
+func (e *MapEditor) Modified() bool {
+	var last mapEdit
+	if len(e.undo) > 0 {
+		last = e.undo[len(e.undo)-1]
+	}
+	return last != e.saved
+}
+
+// Save writes the map in the format of .map files
+func (e *MapEditor) Save(w io.Writer) error {
+	if err := e.Map.WriteYAML(w); err != nil {
+		return err
+	}
+	e.saved = nil
+	if len(e.undo) > 0 {
+		e.saved = e.undo[len(e.undo)-1]
+	}
+	return nil
+}
+
+// AddNode adds a node without any roads at the location
+func (e *MapEditor) AddNode(location engo.Point) *RouteNode {
+	n := &RouteNode{ID: NewMapID(), Location: location}
+	e.do(&addNodeEdit{node: n})
+	return n
+}
+
+// DeleteNode removes the node, and all roads leading to and from it
+func (e *MapEditor) DeleteNode(n *RouteNode) {
+	e.do(&deleteNodeEdit{node: n})
+}
+
+// MoveNode moves the node to the location. Consecutive moves of the same node, like while it's being dragged, are
+// undone at once.
+func (e *MapEditor) MoveNode(n *RouteNode, location engo.Point) {
+	if len(e.undo) > 0 && len(e.redo) == 0 {
+		if last, ok := e.undo[len(e.undo)-1].(*moveNodeEdit); ok && last.node == n && mapEdit(last) != e.saved {
+			last.to = location
+			last.apply(e.Map)
+			return
+		}
+	}
+	e.do(&moveNodeEdit{node: n, from: n.Location, to: location, lengths: e.Map.lengthsAround(n)})
+}
+
+// Connect adds a road from one node to another, replacing any existing roads between them. Unless the road is
+// one-way, the reverse road is added as well. Replaced roads keep their name and house numbers, unless the road has
+// its own.
+func (e *MapEditor) Connect(from, to *RouteNode, road Road) {
+	if from == to {
+		return
+	}
+
+	road.To = to.ID
+	road.Length = from.Location.PointDistance(to.Location)
+	edit := &roadsEdit{a: from, b: to, before: [2]*Road{from.Road(to.ID), to.Road(from.ID)}}
+	forward, backward := edit.before[0], edit.before[1]
+	switch {
+	case forward != nil:
+		road.Name, road.Numbers = keepName(road, forward.Name, forward.Numbers)
+	case backward != nil:
+		road.Name, road.Numbers = keepName(road, backward.Name, backward.Numbers.reversed())
+	}
+	edit.after[0] = &road
+	if !road.OneWay {
+		reverse := road
+		reverse.To, reverse.Numbers = from.ID, road.Numbers.reversed()
+		edit.after[1] = &reverse
+	}
+	e.do(edit)
+}
+
+// keepName returns the name and house numbers of the road, or the given ones for those the road doesn't have
+func keepName(road Road, name string, numbers HouseNumbers) (string, HouseNumbers) {
+	if road.Name != "" {
+		name = road.Name
+	}
+	if !road.Numbers.IsZero() {
+		numbers = road.Numbers
+	}
+	return name, numbers
+}
+
+// SetRoadAttributes changes the class, speed limit and direction of the roads between the nodes to those of attrs,
+// keeping everything else, like their names and house numbers. The road from a to b is changed, or the one from b to a
+// if there is none. Making it one-way removes the reverse road, making it two-way adds it.
+func (e *MapEditor) SetRoadAttributes(a, b *RouteNode, attrs Road) bool {
+	if a.Road(b.ID) == nil {
+		a, b = b, a
+	}
+	edit := &roadsEdit{a: a, b: b, before: [2]*Road{a.Road(b.ID), b.Road(a.ID)}}
+	if edit.before[0] == nil {
+		return false
+	}
+
+	road := *edit.before[0]
+	road.Class, road.SpeedLimit, road.OneWay = attrs.Class, attrs.SpeedLimit, attrs.OneWay
+	edit.after[0] = &road
+	if !attrs.OneWay {
+		reverse := road
+		reverse.To, reverse.Numbers = a.ID, road.Numbers.reversed()
+		if edit.before[1] != nil {
+			reverse = *edit.before[1]
+			reverse.Class, reverse.SpeedLimit, reverse.OneWay = attrs.Class, attrs.SpeedLimit, false
+		}
+		edit.after[1] = &reverse
+	}
+	e.do(edit)
+	return true
+}
+
+// DeleteRoad removes the roads between the nodes, in both directions
+func (e *MapEditor) DeleteRoad(a, b *RouteNode) {
+	edit := &roadsEdit{a: a, b: b, before: [2]*Road{a.Road(b.ID), b.Road(a.ID)}}
+	if edit.before[0] == nil && edit.before[1] == nil {
+		return
+	}
+	e.do(edit)
+}
+
+// moveNode moves the node and recomputes the lengths of the roads leading to and from it
+func (m *Map) moveNode(n *RouteNode, location engo.Point) {
+	m.version++
+	segments := m.incomingRoads(n)
+	for _, road := range n.Roads {
+		if to := m.Node(road.To); to != nil {
+			segments = append(segments, RoadSegment{n, to, road})
+		}
+	}
+
+	index := m.spatial()
+	for _, seg := range segments {
+		index.removeSegment(seg)
+	}
+	index.removeNode(n)
+
+	n.Location = location
+	index.addNode(n)
+	for _, seg := range segments {
+		seg.Road.Length = seg.From.Location.PointDistance(seg.To.Location)
+		index.addSegment(seg)
+		m.roadChanged(seg.From, seg.To, seg.Road)
+	}
+}
+
+// lengthsAround returns the lengths of the roads leading to and from the node
+func (m *Map) lengthsAround(n *RouteNode) map[*Road]float32 {
+	lengths := make(map[*Road]float32)
+	for _, road := range m.IntersectionRoads(n) {
+		lengths[road] = road.Length
+	}
+	return lengths
+}
+
+// replaceRoad replaces the road between the nodes, removing it if road is nil
+func (m *Map) replaceRoad(from, to *RouteNode, road *Road) {
+	if existing := from.Road(to.ID); existing != nil {
+		m.removeRoad(from, existing)
+	}
+	if road != nil {
+		m.addRoad(from, to, road)
+	}
+}
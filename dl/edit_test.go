@@ -0,0 +1,139 @@
+package dl
+
+import (
+	"testing"
+
+	"engo.io/engo"
+)
+
+func TestMoveNodeUndo(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	n := m.NearestNode(engo.Point{200, 200})
+	for _, road := range m.IntersectionRoads(n) {
+		road.Length = 250
+	}
+
+	e := NewMapEditor(m)
+	e.MoveNode(n, engo.Point{210, 200})
+	e.MoveNode(n, engo.Point{220, 200})
+	if l := n.Roads[0].Length; l == 250 {
+		t.Fatal("the length wasn't recomputed")
+	}
+	if !e.Undo() || e.Undo() {
+		t.Fatal("the moves weren't undone at once")
+	}
+	if n.Location != (engo.Point{200, 200}) {
+		t.Fatal(n.Location)
+	}
+	for _, road := range m.IntersectionRoads(n) {
+		if road.Length != 250 {
+			t.Fatal("the length wasn't restored:", road.Length)
+		}
+	}
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetRoadAttributes(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	a, b := m.NearestNode(engo.Point{100, 100}), m.NearestNode(engo.Point{200, 100})
+	ab, ba := a.Road(b.ID), b.Road(a.ID)
+	class := ab.Class
+	ab.Name, ab.Numbers, ab.Length = "Main Street", HouseNumbers{1, 9}, 120
+	ba.Name, ba.Numbers, ba.Length = "Main Street", HouseNumbers{9, 1}, 120
+
+	check := func(from, to *RouteNode, class RoadClass, speedLimit float32, oneWay bool, numbers HouseNumbers) {
+		t.Helper()
+		r := from.Road(to.ID)
+		if r == nil {
+			t.Fatalf("no road from %d to %d", from.ID, to.ID)
+		}
+		if r.Class != class || r.SpeedLimit != speedLimit || r.OneWay != oneWay || r.Name != "Main Street" ||
+			r.Numbers != numbers || r.Length != 120 {
+			t.Fatalf("road from %d to %d is %+v", from.ID, to.ID, r)
+		}
+	}
+
+	e := NewMapEditor(m)
+	if !e.SetRoadAttributes(a, b, Road{Class: RoadArterial, SpeedLimit: 80}) {
+		t.Fatal("the road wasn't changed")
+	}
+	check(a, b, RoadArterial, 80, false, HouseNumbers{1, 9})
+	check(b, a, RoadArterial, 80, false, HouseNumbers{9, 1})
+
+	// Changed from the other end, the one-way road keeps going from a to b
+	e.SetRoadAttributes(a, b, Road{Class: RoadArterial, OneWay: true})
+	e.SetRoadAttributes(b, a, Road{Class: RoadHighway, OneWay: true})
+	check(a, b, RoadHighway, 0, true, HouseNumbers{1, 9})
+	if b.Road(a.ID) != nil {
+		t.Fatal("the one-way road can still be driven the other way")
+	}
+	e.SetRoadAttributes(a, b, Road{Class: RoadHighway})
+	check(b, a, RoadHighway, 0, false, HouseNumbers{9, 1})
+
+	// Every change is undone at once
+	for i := 0; i < 4; i++ {
+		if !e.Undo() {
+			t.Fatal("nothing to undo")
+		}
+	}
+	check(a, b, class, 0, false, HouseNumbers{1, 9})
+	check(b, a, class, 0, false, HouseNumbers{9, 1})
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := m.NearestNode(engo.Point{300, 300})
+	if e.SetRoadAttributes(a, c, Road{}) {
+		t.Fatal("changed a road which doesn't exist")
+	}
+}
+
+func TestConnectKeepsName(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	a, b := m.NearestNode(engo.Point{100, 100}), m.NearestNode(engo.Point{200, 100})
+	ab, ba := a.Road(b.ID), b.Road(a.ID)
+	ab.Name, ab.Numbers = "Main Street", HouseNumbers{1, 9}
+	ba.Name, ba.Numbers = "Main Street", HouseNumbers{9, 1}
+
+	check := func(from, to *RouteNode, class RoadClass, name string, numbers HouseNumbers) {
+		t.Helper()
+		r := from.Road(to.ID)
+		if r == nil {
+			t.Fatalf("no road from %d to %d", from.ID, to.ID)
+		}
+		if r.Class != class || r.Name != name || r.Numbers != numbers {
+			t.Fatalf("road from %d to %d is %+v", from.ID, to.ID, r)
+		}
+	}
+
+	e := NewMapEditor(m)
+	e.Connect(a, b, Road{Class: RoadAlley})
+	check(a, b, RoadAlley, "Main Street", HouseNumbers{1, 9})
+	check(b, a, RoadAlley, "Main Street", HouseNumbers{9, 1})
+
+	// Replaced from the other end, the one-way road takes the numbers of the road it replaces
+	e.Connect(b, a, Road{Class: RoadArterial, OneWay: true})
+	check(b, a, RoadArterial, "Main Street", HouseNumbers{9, 1})
+	if a.Road(b.ID) != nil {
+		t.Fatal("the one-way road can still be driven the other way")
+	}
+
+	// A road with a name of its own keeps it
+	e.Connect(a, b, Road{Class: RoadResidential, Name: "High Street"})
+	check(a, b, RoadResidential, "High Street", HouseNumbers{1, 9})
+	check(b, a, RoadResidential, "High Street", HouseNumbers{9, 1})
+
+	// New roads get no name
+	c := m.NearestNode(engo.Point{300, 300})
+	e.Connect(a, c, Road{})
+	check(a, c, RoadResidential, "", HouseNumbers{})
+
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
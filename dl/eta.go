@@ -0,0 +1,291 @@
+package dl
+
+import (
+	"image/color"
+	"log"
+	"sort"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+	"github.com/luxengine/math"
+)
+
+const isochroneViewKey = "isochrone-viewing-key"
+
+// isochroneRefresh is the time (in seconds) between updates of the isochrone overlay
+const isochroneRefresh = 0.5
+
+// TravelTimes holds how long (in seconds) it takes a unit to drive from its position to every node it can reach
+type TravelTimes struct {
+	Map      *Map
+	Source   RoadPosition
+	MaxSpeed float32
+
+	times map[*RouteNode]float32
+}
+
+// TravelTimesFrom computes the travel times from the position for a unit driving at most at maxSpeed. Just like with
+// FindRoute, closed roads are only used to leave the road the unit is on.
+func (m *Map) TravelTimesFrom(source RoadPosition, maxSpeed float32) *TravelTimes {
+	t := &TravelTimes{Map: m, Source: source, MaxSpeed: maxSpeed, times: make(map[*RouteNode]float32)}
+
+	var queue PriorityQueue[*RouteNode]
+	reach := func(n *RouteNode, time float32) {
+		if known, ok := t.times[n]; !ok || time < known {
+			t.times[n] = time
+			queue.Enqueue(n, time)
+		}
+	}
+
+	// Both ends of the road the unit is on are the sources of the search
+	if source.OnRoad() {
+		reach(source.To, source.Road.TravelTime(maxSpeed)*(1-source.Fraction()))
+		if reverse := source.To.Road(source.From.ID); reverse != nil {
+			reach(source.From, reverse.TravelTime(maxSpeed)*source.Fraction())
+		}
+	} else if n := m.NearestNode(source.Point); n != nil {
+		reach(n, 0)
+	}
+
+	for queue.Len() > 0 {
+		n, time, _ := queue.TryDequeue()
+		for _, road := range n.Roads {
+			if road.Closed() {
+				continue
+			}
+			if to := m.Node(road.To); to != nil {
+				reach(to, time+road.TravelTime(maxSpeed))
+			}
+		}
+	}
+	return t
+}
+
+// Node returns how long it takes to get to the node, and false if it can't be reached
+func (t *TravelTimes) Node(n *RouteNode) (float32, bool) {
+	time, ok := t.times[n]
+	return time, ok
+}
+
+// ETA returns how long it takes to get to the point on the road closest to p, and false if it can't be reached
+func (t *TravelTimes) ETA(p engo.Point) (float32, bool) {
+	pos := t.Map.SnapToRoad(p)
+	if !pos.OnRoad() {
+		if n := t.Map.NearestNode(p); n != nil {
+			return t.Node(n)
+		}
+		return 0, false
+	}
+
+	eta := unreachable
+	if time, ok := t.times[pos.From]; ok {
+		eta = math.Min(eta, time+pos.Road.TravelTime(t.MaxSpeed)*pos.Fraction())
+	}
+	reverse := pos.To.Road(pos.From.ID)
+	if time, ok := t.times[pos.To]; ok && reverse != nil {
+		eta = math.Min(eta, time+reverse.TravelTime(t.MaxSpeed)*(1-pos.Fraction()))
+	}
+
+	// The point may be on the road the unit is on, in which case it can drive there directly
+	if src := t.Source; src.OnRoad() && (src.Road == pos.Road || src.Road == reverse) {
+		from := src.Fraction()
+		if src.Road == reverse {
+			from = 1 - from
+		}
+		if to := pos.Fraction(); to >= from {
+			eta = math.Min(eta, pos.Road.TravelTime(t.MaxSpeed)*(to-from))
+		} else if reverse != nil {
+			eta = math.Min(eta, reverse.TravelTime(t.MaxSpeed)*(from-to))
+		}
+	}
+
+	return eta, eta != unreachable
+}
+
+// Isochrone returns the area which can be reached within the given time, as the convex hull of all roads (and parts
+// of roads) that can be driven in that time. The corners of the polygon are in counter-clockwise order.
+func (t *TravelTimes) Isochrone(seconds float32) []engo.Point {
+	points := []engo.Point{t.Source.Point}
+
+	// reach adds the part of the road between the points which can be driven in time
+	reach := func(from, to engo.Point, start, cost float32) {
+		switch {
+		case start > seconds:
+		case start+cost <= seconds:
+			points = append(points, from, to)
+		default:
+			fraction := (seconds - start) / cost
+			points = append(points, from, engo.Point{
+				X: from.X + (to.X-from.X)*fraction,
+				Y: from.Y + (to.Y-from.Y)*fraction,
+			})
+		}
+	}
+
+	if src := t.Source; src.OnRoad() {
+		reach(src.Point, src.To.Location, 0, src.Road.TravelTime(t.MaxSpeed)*(1-src.Fraction()))
+		if reverse := src.To.Road(src.From.ID); reverse != nil {
+			reach(src.Point, src.From.Location, 0, reverse.TravelTime(t.MaxSpeed)*src.Fraction())
+		}
+	}
+	for n, time := range t.times {
+		for _, road := range n.Roads {
+			if to := t.Map.Node(road.To); to != nil && !road.Closed() {
+				reach(n.Location, to.Location, time, road.TravelTime(t.MaxSpeed))
+			}
+		}
+	}
+
+	return convexHull(points)
+}
+
+// TravelTimes computes how long it takes the unit to get anywhere on the CurrentMap from where it is now
+func (p *PoliceComponent) TravelTimes() *TravelTimes {
+	return CurrentMap.TravelTimesFrom(CurrentMap.SnapToRoad(*p.Location), p.Unit.Speed)
+}
+
+// convexHull returns the smallest convex polygon containing all points, in counter-clockwise order
+func convexHull(points []engo.Point) []engo.Point {
+	points = append([]engo.Point(nil), points...)
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X == points[j].X {
+			return points[i].Y < points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+	if len(points) < 3 {
+		return points
+	}
+
+	cross := func(o, a, b engo.Point) float32 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+
+	// Andrew's monotone chain: the lower half from left to right, and then the upper half back
+	hull := make([]engo.Point, 0, 2*len(points))
+	for _, half := range [2]int{1, -1} {
+		start := len(hull)
+		for i := range points {
+			p := points[i]
+			if half < 0 {
+				p = points[len(points)-1-i]
+			}
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		// The last point is the first of the other half
+		hull = hull[:len(hull)-1]
+	}
+	return hull
+}
+
+type polygonEntity struct {
+	ecs.BasicEntity
+	common.RenderComponent
+	common.SpaceComponent
+}
+
+// IsochroneDebugSystem draws the areas every unit can reach within the given times, when toggled with F3
+type IsochroneDebugSystem struct {
+	// Times are the travel times (in seconds) for which the areas are drawn, from small to large
+	Times []float32
+
+	rs       *common.RenderSystem
+	visible  bool
+	elapsed  float32
+	entities []*polygonEntity
+}
+
+func (d *IsochroneDebugSystem) New(w *ecs.World) {
+	if len(d.Times) == 0 {
+		d.Times = []float32{15, 30, 60}
+	}
+	for _, system := range w.Systems() {
+		if rs, ok := system.(*common.RenderSystem); ok {
+			d.rs = rs
+		}
+	}
+	engo.Input.RegisterButton(isochroneViewKey, engo.F3)
+}
+
+func (d *IsochroneDebugSystem) Remove(b ecs.BasicEntity) {}
+
+func (d *IsochroneDebugSystem) Update(dt float32) {
+	if engo.Input.Button(isochroneViewKey).JustPressed() {
+		d.visible = !d.visible
+		d.elapsed = isochroneRefresh
+		log.Println("IsochroneDebugView:", d.visible)
+	}
+
+	d.elapsed += dt
+	if d.elapsed < isochroneRefresh {
+		return
+	}
+	d.elapsed = 0
+
+	for _, e := range d.entities {
+		d.rs.Remove(e.BasicEntity)
+	}
+	d.entities = d.entities[:0]
+	if !d.visible || CurrentMap == nil {
+		return
+	}
+
+	for _, unit := range police {
+		times := unit.TravelTimes()
+		// The largest area is drawn first, so the smaller ones are drawn on top of it
+		for i := len(d.Times) - 1; i >= 0; i-- {
+			e := newIsochroneEntity(times.Isochrone(d.Times[i]), ui.IsochroneColors[i%len(ui.IsochroneColors)])
+			if e == nil {
+				continue
+			}
+			d.entities = append(d.entities, e)
+			d.rs.Add(&e.BasicEntity, &e.RenderComponent, &e.SpaceComponent)
+		}
+	}
+}
+
+// newIsochroneEntity draws the convex polygon as a fan of triangles, or returns nil if it has no area
+func newIsochroneEntity(polygon []engo.Point, c color.Color) *polygonEntity {
+	if len(polygon) < 3 {
+		return nil
+	}
+
+	var triangles []engo.Point
+	for i := 1; i+1 < len(polygon); i++ {
+		triangles = append(triangles, polygon[0], polygon[i], polygon[i+1])
+	}
+	e := newPolygonEntity(triangles, c)
+	e.RenderComponent.SetZIndex(1)
+	return e
+}
+
+// newPolygonEntity draws the triangles, given by their corners, or returns nil if there are none
+func newPolygonEntity(triangles []engo.Point, c color.Color) *polygonEntity {
+	if len(triangles) < 3 {
+		return nil
+	}
+
+	min, max := triangles[0], triangles[0]
+	for _, p := range triangles {
+		min.X, min.Y = math.Min(min.X, p.X), math.Min(min.Y, p.Y)
+		max.X, max.Y = math.Max(max.X, p.X), math.Max(max.Y, p.Y)
+	}
+	width, height := math.Max(max.X-min.X, 1), math.Max(max.Y-min.Y, 1)
+
+	// The points of the drawable are relative to the size of the polygon
+	relative := make([]engo.Point, len(triangles))
+	for i, p := range triangles {
+		relative[i] = engo.Point{(p.X - min.X) / width, (p.Y - min.Y) / height}
+	}
+
+	return &polygonEntity{
+		BasicEntity:     ecs.NewBasic(),
+		RenderComponent: common.RenderComponent{Drawable: common.ComplexTriangles{Points: relative}, Color: c},
+		SpaceComponent:  common.SpaceComponent{Position: min, Width: width, Height: height},
+	}
+}
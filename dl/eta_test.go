@@ -0,0 +1,164 @@
+package dl
+
+import (
+	"fmt"
+	"testing"
+
+	"engo.io/engo"
+	"github.com/luxengine/math"
+)
+
+// etaTestMap returns a square block of 100 by 100 with one one-way road, from 2 to 3, and a one-way road from 5
+// leading into it, which can't be reached from the block:
+//
+//	4 --- 3
+//	|     ^
+//	1 --- 2 <- 5
+func etaTestMap() *Map {
+	m := &Map{Nodes: []*RouteNode{
+		{ID: 1, Location: engo.Point{X: 0, Y: 0}, ConnectedTo: []uint32{2, 4}},
+		{ID: 2, Location: engo.Point{X: 100, Y: 0}},
+		{ID: 3, Location: engo.Point{X: 100, Y: 100}, ConnectedTo: []uint32{4}},
+		{ID: 4, Location: engo.Point{X: 0, Y: 100}},
+		{ID: 5, Location: engo.Point{X: 200, Y: 0}},
+	}}
+	m.Nodes[1].Roads = []*Road{{To: 3, OneWay: true}}
+	m.Nodes[4].Roads = []*Road{{To: 2, OneWay: true}}
+	m.Initialize()
+	return m
+}
+
+// etaSpeed is the speed of 10 m/s used in the tests, which is below the speed limit of every road
+const etaSpeed = 36
+
+func checkTime(t *testing.T, what string, time float32, ok bool, expected float32) {
+	t.Helper()
+	if !ok {
+		t.Fatalf("%s can't be reached, expected %.1f s", what, expected)
+	}
+	if math.Abs(time-expected) > 1e-3 {
+		t.Fatalf("%s takes %.3f s, expected %.1f s", what, time, expected)
+	}
+}
+
+func TestTravelTimesFrom(t *testing.T) {
+	m := etaTestMap()
+	times := m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 30, Y: 0}), etaSpeed)
+
+	for id, expected := range map[uint32]float32{1: 3, 2: 7, 3: 17, 4: 13} {
+		time, ok := times.Node(m.Node(id))
+		checkTime(t, fmt.Sprint("node ", id), time, ok, expected)
+	}
+	if _, ok := times.Node(m.Node(5)); ok {
+		t.Fatal("reached a node from which the road only leads into the block")
+	}
+	if _, ok := times.ETA(engo.Point{X: 200, Y: 0}); ok {
+		t.Fatal("reached a point on a road which can't be driven to")
+	}
+}
+
+func TestETASameRoad(t *testing.T) {
+	m := etaTestMap()
+	times := m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 30, Y: 0}), etaSpeed)
+
+	// Points on the road the unit is on are driven to directly, in either direction
+	eta, ok := times.ETA(engo.Point{X: 80, Y: 0})
+	checkTime(t, "ahead on the same road", eta, ok, 5)
+	eta, ok = times.ETA(engo.Point{X: 10, Y: 5})
+	checkTime(t, "behind on the same road", eta, ok, 2)
+	eta, ok = times.ETA(engo.Point{X: 0, Y: 40})
+	checkTime(t, "around the corner", eta, ok, 7)
+}
+
+func TestETAOneWay(t *testing.T) {
+	m := etaTestMap()
+	times := m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 100, Y: 30}), etaSpeed)
+
+	eta, ok := times.ETA(engo.Point{X: 100, Y: 60})
+	checkTime(t, "ahead on the one-way road", eta, ok, 3)
+
+	// Going back means driving around the block
+	time, ok := times.Node(m.Node(2))
+	checkTime(t, "the start of the one-way road", time, ok, 37)
+	eta, ok = times.ETA(engo.Point{X: 100, Y: 10})
+	checkTime(t, "behind on the one-way road", eta, ok, 38)
+}
+
+func TestETAClosedRoads(t *testing.T) {
+	m := etaTestMap()
+	m.CloseRoads(m.RoadsAt(m.SnapToRoad(engo.Point{X: 0, Y: 50}))...)
+
+	times := m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 30, Y: 0}), etaSpeed)
+	time, ok := times.Node(m.Node(4))
+	checkTime(t, "the far end of the closed road", time, ok, 27)
+
+	// Once the one-way road is closed too, nothing beyond it can be reached
+	r := m.CloseRoads(m.RoadsAt(m.SnapToRoad(engo.Point{X: 100, Y: 50}))...)
+	times = m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 30, Y: 0}), etaSpeed)
+	if _, ok := times.Node(m.Node(4)); ok {
+		t.Fatal("drove through a closed road to reach the other side")
+	}
+
+	// A unit on a closed road can still leave it
+	times = m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 100, Y: 30}), etaSpeed)
+	time, ok = times.Node(m.Node(3))
+	checkTime(t, "the end of the closed road the unit is on", time, ok, 7)
+
+	m.LiftRestriction(r)
+	times = m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 30, Y: 0}), etaSpeed)
+	time, ok = times.Node(m.Node(4))
+	checkTime(t, "the far end once the road was opened", time, ok, 27)
+}
+
+// polygonArea returns the area of the polygon, which is positive if its corners are in counter-clockwise order
+func polygonArea(polygon []engo.Point) float32 {
+	var area float32
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	return area / 2
+}
+
+func TestIsochrone(t *testing.T) {
+	m := etaTestMap()
+	times := m.TravelTimesFrom(m.SnapToRoad(engo.Point{X: 30, Y: 0}), etaSpeed)
+
+	// After 5 s the unit got to (80, 0) ahead, or around the corner to (0, 20)
+	if area := polygonArea(times.Isochrone(5)); math.Abs(area-800) > 1e-2 {
+		t.Fatalf("area after 5 s is %.2f, expected 800", area)
+	}
+
+	var last float32
+	for _, seconds := range []float32{1, 5, 10, 15, 20, 40} {
+		area := polygonArea(times.Isochrone(seconds))
+		if area < last {
+			t.Fatalf("area after %.0f s shrunk from %.2f to %.2f", seconds, last, area)
+		}
+		last = area
+	}
+	if math.Abs(last-100*100) > 1e-2 {
+		t.Fatalf("area once everything is reached is %.2f, expected the whole block", last)
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	points := []engo.Point{
+		{X: 5, Y: 5}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 5, Y: 0},
+		{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 2, Y: 8}, {X: 0, Y: 5},
+	}
+	expected := []engo.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+
+	hull := convexHull(points)
+	if len(hull) != len(expected) {
+		t.Fatalf("hull is %v, expected %v", hull, expected)
+	}
+	for i := range hull {
+		if hull[i] != expected[i] {
+			t.Fatalf("hull is %v, expected %v", hull, expected)
+		}
+	}
+	if points[0] != (engo.Point{X: 5, Y: 5}) {
+		t.Fatal("the points were reordered")
+	}
+}
@@ -0,0 +1,280 @@
+package dl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"engo.io/engo"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// link is a road as it's exported: roads which are the same in both directions are exported only once
+type link struct {
+	From, To *RouteNode
+	Road     Road
+	TwoWay   bool
+}
+
+// exporter collects the nodes and roads of a map in a stable order, so exporting the same map always gives the
+// same result. Temporary nodes are left out, and the roads they split are joined again.
+type exporter struct {
+	nodes []*RouteNode
+	links []link
+}
+
+func newExporter(m *Map) *exporter {
+	e := &exporter{}
+	ids := make(map[uint32]*RouteNode, len(m.Nodes))
+	for _, node := range m.Nodes {
+		ids[node.ID] = node
+		if !node.Temporary {
+			e.nodes = append(e.nodes, node)
+		}
+	}
+	sort.Slice(e.nodes, func(i, j int) bool { return e.nodes[i].ID < e.nodes[j].ID })
+
+	roads := make(map[*RouteNode][]Road, len(e.nodes))
+	for _, node := range e.nodes {
+		roads[node] = exportRoads(node, ids)
+	}
+
+	for _, node := range e.nodes {
+		for _, road := range roads[node] {
+			to := ids[road.To]
+			reverse := findRoad(roads[to], node.ID)
+			switch {
+			case reverse == nil:
+				// Without this, the reverse road would be added when loading the map
+				road.OneWay = true
+			case !road.OneWay && !reverse.OneWay && sameRoad(road, *reverse):
+				if to.ID < node.ID {
+					continue
+				}
+				e.links = append(e.links, link{node, to, road, true})
+				continue
+			}
+			e.links = append(e.links, link{node, to, road, false})
+		}
+	}
+	return e
+}
+
+// exportRoads returns the roads of the node sorted by target, following roads to temporary nodes until the
+// original target is reached
+func exportRoads(n *RouteNode, ids map[uint32]*RouteNode) []Road {
+	var roads []Road
+	for _, road := range n.Roads {
+		// Restrictions and traffic are not part of the map itself
+		r := *road
+		r.restrictions, r.traffic = nil, 0
+		prev, curr := n, ids[r.To]
+		for curr != nil && curr.Temporary {
+			var next *Road
+			for _, out := range curr.Roads {
+				if out.To != prev.ID {
+					next = out
+					break
+				}
+			}
+			if next == nil {
+				curr = nil
+				break
+			}
+			r.To, r.Length, r.Numbers.Last = next.To, r.Length+next.Length, next.Numbers.Last
+			prev, curr = curr, ids[next.To]
+		}
+		if curr == nil || curr == n || findRoad(roads, r.To) != nil {
+			continue
+		}
+		if r.whole != 0 {
+			r.Length, r.whole = r.whole, 0
+		}
+		roads = append(roads, r)
+	}
+	sort.Slice(roads, func(i, j int) bool { return roads[i].To < roads[j].To })
+	return roads
+}
+
+func findRoad(roads []Road, to uint32) *Road {
+	for i := range roads {
+		if roads[i].To == to {
+			return &roads[i]
+		}
+	}
+	return nil
+}
+
+func sameRoad(a, b Road) bool {
+	return a.Class == b.Class && a.Length == b.Length && a.SpeedLimit == b.SpeedLimit && a.Name == b.Name &&
+		a.Numbers == b.Numbers.reversed()
+}
+
+type yamlMap struct {
+	Name  string     `yaml:"name,omitempty"`
+	Nodes []yamlNode `yaml:"nodes"`
+	Zones []*Zone    `yaml:"zones,omitempty"`
+	POIs  []*POI     `yaml:"pois,omitempty"`
+}
+
+type yamlNode struct {
+	ID          uint32     `yaml:"id"`
+	Location    engo.Point `yaml:"location"`
+	ConnectedTo []uint32   `yaml:"connectedTo,omitempty"`
+	Roads       []Road     `yaml:"roads,omitempty"`
+}
+
+// WriteYAML writes the map in the format of .map files. Reading the result with ParseMap gives the same map, and
+// writing that again gives the same result. Two-way roads are written only once, and plain residential roads
+// as connectedTo.
+func (m *Map) WriteYAML(w io.Writer) error {
+	e := newExporter(m)
+	def := yamlMap{Name: m.Name, Nodes: make([]yamlNode, len(e.nodes)), Zones: m.Zones, POIs: m.POIs}
+	index := make(map[*RouteNode]int, len(e.nodes))
+	for i, node := range e.nodes {
+		def.Nodes[i] = yamlNode{ID: node.ID, Location: node.Location}
+		index[node] = i
+	}
+
+	for _, l := range e.links {
+		node := &def.Nodes[index[l.From]]
+		road := l.Road
+		// The length is left out if it would be computed the same when loading the map
+		if road.Length == l.From.Location.PointDistance(l.To.Location) {
+			road.Length = 0
+		}
+		if l.TwoWay && road.Class == RoadResidential && road.Length == 0 && road.SpeedLimit == 0 && road.Name == "" &&
+			road.Numbers.IsZero() {
+			node.ConnectedTo = append(node.ConnectedTo, road.To)
+			continue
+		}
+		node.Roads = append(node.Roads, road)
+	}
+
+	b, err := yaml.Marshal(def)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONPosition flips the y-axis, which points north in GIS tools
+func geoJSONPosition(p engo.Point) [2]float32 {
+	return [2]float32{p.X, -p.Y}
+}
+
+// WriteGeoJSON writes the map as a GeoJSON FeatureCollection, with a Point for every node and a LineString for every
+// road. Coordinates are in map units rather than longitude and latitude.
+func (m *Map) WriteGeoJSON(w io.Writer) error {
+	e := newExporter(m)
+	features := make([]geoJSONFeature, 0, len(e.nodes)+len(e.links))
+	for _, node := range e.nodes {
+		features = append(features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{"Point", geoJSONPosition(node.Location)},
+			Properties: map[string]interface{}{"id": node.ID},
+		})
+	}
+	for _, l := range e.links {
+		props := map[string]interface{}{
+			"from":   l.From.ID,
+			"to":     l.To.ID,
+			"class":  l.Road.Class.String(),
+			"length": l.Road.Length,
+			"speed":  l.Road.Speed(0),
+			"oneWay": !l.TwoWay,
+		}
+		if l.Road.Name != "" {
+			props["name"] = l.Road.Name
+		}
+		if !l.Road.Numbers.IsZero() {
+			props["numbers"] = [2]int{l.Road.Numbers.First, l.Road.Numbers.Last}
+		}
+		features = append(features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{"LineString", [][2]float32{
+				geoJSONPosition(l.From.Location), geoJSONPosition(l.To.Location),
+			}},
+			Properties: props,
+		})
+	}
+
+	for _, z := range m.Zones {
+		ring := make([][2]float32, 0, len(z.Polygon)+1)
+		for _, p := range z.Polygon {
+			ring = append(ring, geoJSONPosition(p))
+		}
+		// The first and last positions of a ring are the same
+		ring = append(ring, ring[0])
+		props := map[string]interface{}{"name": z.Name, "kind": z.Kind.String()}
+		if z.IncidentRate > 0 {
+			props["incidentRate"] = z.IncidentRate
+		}
+		features = append(features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{"Polygon", [][][2]float32{ring}},
+			Properties: props,
+		})
+	}
+
+	for _, poi := range m.POIs {
+		props := map[string]interface{}{"kind": poi.Kind.String()}
+		if poi.Name != "" {
+			props["name"] = poi.Name
+		}
+		features = append(features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{"Point", geoJSONPosition(poi.Location)},
+			Properties: props,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Type     string           `json:"type"`
+		Name     string           `json:"name,omitempty"`
+		Features []geoJSONFeature `json:"features"`
+	}{"FeatureCollection", m.Name, features})
+}
+
+// WriteDOT writes the map as a Graphviz graph. The nodes are positioned as on the map, so use neato -n to render it.
+func (m *Map) WriteDOT(w io.Writer) error {
+	e := newExporter(m)
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintf(buf, "digraph %s {\n", strconv.Quote(m.Name))
+	fmt.Fprintln(buf, "\tnode [shape=point];")
+	for _, node := range e.nodes {
+		fmt.Fprintf(buf, "\t%d [pos=\"%g,%g\"];\n", node.ID, node.Location.X, -node.Location.Y)
+	}
+	for _, l := range e.links {
+		label := fmt.Sprintf("%s %.0fm", l.Road.Class, l.Road.Length)
+		if l.Road.Name != "" {
+			label = l.Road.Name + "\n" + label
+		}
+		dir := ""
+		if l.TwoWay {
+			dir = ", dir=both"
+		}
+		fmt.Fprintf(buf, "\t%d -> %d [label=%s%s];\n", l.From.ID, l.To.ID, strconv.Quote(label), dir)
+	}
+	fmt.Fprintln(buf, "}")
+
+	return buf.Flush()
+}
@@ -0,0 +1,74 @@
+package dl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"engo.io/engo"
+)
+
+// checkRoundTrip writes the map, reads it again and checks that writing that gives exactly the same result
+func checkRoundTrip(t *testing.T, m *Map) []byte {
+	t.Helper()
+	var first, second bytes.Buffer
+	if err := m.WriteYAML(&first); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ParseMap(first.Bytes(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := read.WriteYAML(&second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatalf("writing the map again gave a different result:\n%s\n\n%s", first.Bytes(), second.Bytes())
+	}
+
+	for _, node := range read.Nodes {
+		orig := m.Node(node.ID)
+		if orig == nil || orig.Temporary || orig.Location != node.Location {
+			t.Fatalf("node %v was %v", node, orig)
+		}
+		for _, road := range node.Roads {
+			if o := orig.Road(road.To); o != nil && (o.Class != road.Class || o.Name != road.Name ||
+				o.SpeedLimit != road.SpeedLimit || o.OneWay != road.OneWay || o.Numbers != road.Numbers) {
+				t.Fatalf("road from %d to %d is %+v, but was %+v", node.ID, road.To, road, o)
+			}
+		}
+	}
+	return first.Bytes()
+}
+
+func TestWriteYAMLRoundTrip(t *testing.T) {
+	b, err := ioutil.ReadFile("../assets/maps/1.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseMap(b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRoundTrip(t, m)
+
+	city := GenerateCity(DefaultCityOptions(6))
+	city.prepare()
+	checkRoundTrip(t, city)
+}
+
+func TestWriteYAMLTemporary(t *testing.T) {
+	m := GenerateCity(DefaultCityOptions(7))
+	m.prepare()
+	want := checkRoundTrip(t, m)
+
+	// Temporary nodes are left out, and the roads they split are written as they were
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 50; i++ {
+		m.AcquireNode(m.SnapToRoad(engo.Point{X: rng.Float32() * 2000, Y: rng.Float32() * 2000}))
+	}
+	if got := checkRoundTrip(t, m); !bytes.Equal(got, want) {
+		t.Fatalf("the temporary nodes changed the map:\n%s\n\n%s", got, want)
+	}
+}
@@ -0,0 +1,247 @@
+package dl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"engo.io/engo"
+	"github.com/luxengine/math"
+)
+
+// intersectionRadius is the distance to an intersection within which positions are described by the intersection
+const intersectionRadius = 15
+
+var ErrUnknownAddress = errors.New("unknown address")
+
+// streetAbbreviations are the abbreviations which can be used in addresses
+var streetAbbreviations = map[string]string{
+	"st":   "street",
+	"ave":  "avenue",
+	"av":   "avenue",
+	"rd":   "road",
+	"blvd": "boulevard",
+	"dr":   "drive",
+	"ln":   "lane",
+	"pl":   "place",
+	"sq":   "square",
+	"hwy":  "highway",
+}
+
+// HouseNumbers is the range of house numbers along a road, from its start to its end. Both are 0 if the road has no
+// house numbers.
+type HouseNumbers struct {
+	First int `yaml:"first"`
+	Last  int `yaml:"last"`
+}
+
+// IsZero indicates whether or not there are no house numbers, in which case they are left out of map files
+func (h HouseNumbers) IsZero() bool {
+	return h == HouseNumbers{}
+}
+
+// At returns the house number at the given fraction of the road
+func (h HouseNumbers) At(fraction float32) int {
+	// The number is rounded to the nearest one, halfway rounding up
+	return h.First + int(math.Floor(float32(h.Last-h.First)*fraction+0.5))
+}
+
+// fraction returns where along the road the house number is, and false if it's not on the road
+func (h HouseNumbers) fraction(number int) (float32, bool) {
+	switch {
+	case h.IsZero():
+		return 0, false
+	case h.First == h.Last:
+		return 0.5, number == h.First
+	}
+	f := float32(number-h.First) / float32(h.Last-h.First)
+	return f, f >= 0 && f <= 1
+}
+
+func (h HouseNumbers) reversed() HouseNumbers {
+	return HouseNumbers{h.Last, h.First}
+}
+
+// split returns the house numbers of the two roads the road is split into at the fraction
+func (h HouseNumbers) split(fraction float32) (HouseNumbers, HouseNumbers) {
+	if h.IsZero() {
+		return h, h
+	}
+	at := h.At(fraction)
+	return HouseNumbers{h.First, at}, HouseNumbers{at, h.Last}
+}
+
+// normalizeStreet makes street names comparable, ignoring case, punctuation and abbreviations
+func normalizeStreet(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if full, ok := streetAbbreviations[word]; ok {
+			words[i] = full
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// streetIndex holds all road segments of every street, by their normalized name
+type streetIndex struct {
+	version  uint64
+	segments map[string][]RoadSegment
+}
+
+// street returns the road segments of the street, indexing all streets if the map changed since the last time
+func (m *Map) street(name string) []RoadSegment {
+	if m.streets == nil || m.streets.version != m.version {
+		m.streets = &streetIndex{version: m.version, segments: make(map[string][]RoadSegment)}
+		for _, node := range m.Nodes {
+			for _, road := range node.Roads {
+				to := m.Node(road.To)
+				if to == nil || road.Name == "" {
+					continue
+				}
+				name := normalizeStreet(road.Name)
+				m.streets.segments[name] = append(m.streets.segments[name], RoadSegment{node, to, road})
+			}
+		}
+		for _, segments := range m.streets.segments {
+			sort.Slice(segments, func(i, j int) bool {
+				if segments[i].From.ID == segments[j].From.ID {
+					return segments[i].To.ID < segments[j].To.ID
+				}
+				return segments[i].From.ID < segments[j].From.ID
+			})
+		}
+	}
+	return m.streets.segments[normalizeStreet(name)]
+}
+
+// Geocode returns the position of the address, which is either a house number and a street ("12 Elm Street"), two
+// streets that cross ("Main St & 5th Ave") or just a street, in which case its middle is used.
+func (m *Map) Geocode(address string) (RoadPosition, error) {
+	if streets := strings.SplitN(address, "&", 2); len(streets) == 2 {
+		return m.geocodeIntersection(streets[0], streets[1])
+	}
+
+	fields := strings.Fields(address)
+	if len(fields) > 1 {
+		if number, err := strconv.Atoi(strings.TrimSuffix(fields[0], ",")); err == nil {
+			return m.geocodeHouse(number, strings.Join(fields[1:], " "))
+		}
+	}
+
+	segments := m.street(address)
+	if len(segments) == 0 {
+		return RoadPosition{}, fmt.Errorf("%w: no street called %q", ErrUnknownAddress, strings.TrimSpace(address))
+	}
+	seg := segments[len(segments)/2]
+	return m.PositionAt(seg, seg.Road.Length/2), nil
+}
+
+func (m *Map) geocodeHouse(number int, street string) (RoadPosition, error) {
+	segments := m.street(street)
+	if len(segments) == 0 {
+		return RoadPosition{}, fmt.Errorf("%w: no street called %q", ErrUnknownAddress, street)
+	}
+
+	// If the house number doesn't exist, the closest one that does is used
+	var (
+		closest  RoadSegment
+		fraction float32
+		distance = -1
+	)
+	for _, seg := range segments {
+		numbers := seg.Road.Numbers
+		if f, ok := numbers.fraction(number); ok {
+			return m.PositionAt(seg, f*seg.Road.Length), nil
+		}
+		if numbers.IsZero() {
+			continue
+		}
+		for _, end := range [2]struct {
+			number   int
+			fraction float32
+		}{{numbers.First, 0}, {numbers.Last, 1}} {
+			d := end.number - number
+			if d < 0 {
+				d = -d
+			}
+			if distance < 0 || d < distance {
+				closest, fraction, distance = seg, end.fraction, d
+			}
+		}
+	}
+	if distance < 0 {
+		return RoadPosition{}, fmt.Errorf("%w: %q has no house numbers", ErrUnknownAddress, street)
+	}
+	return m.PositionAt(closest, fraction*closest.Road.Length), nil
+}
+
+func (m *Map) geocodeIntersection(a, b string) (RoadPosition, error) {
+	segmentsA, segmentsB := m.street(a), m.street(b)
+	nodes := make(map[*RouteNode]struct{})
+	for _, seg := range segmentsA {
+		nodes[seg.From], nodes[seg.To] = struct{}{}, struct{}{}
+	}
+	for _, seg := range segmentsB {
+		if _, ok := nodes[seg.From]; ok {
+			return m.PositionAt(seg, 0), nil
+		}
+		if _, ok := nodes[seg.To]; ok {
+			return m.PositionAt(seg, seg.Road.Length), nil
+		}
+	}
+	return RoadPosition{}, fmt.Errorf("%w: %q and %q don't cross", ErrUnknownAddress, strings.TrimSpace(a),
+		strings.TrimSpace(b))
+}
+
+// Address describes the position in words: the intersection it's at, or the house number and street it's on. If the
+// road has no name, the coordinates are used instead.
+func (m *Map) Address(pos RoadPosition) string {
+	if !pos.OnRoad() {
+		return fmt.Sprintf("%.0f, %.0f", pos.Point.X, pos.Point.Y)
+	}
+
+	if pos.Offset <= intersectionRadius {
+		if names := m.streetNames(pos.From); len(names) > 1 {
+			return strings.Join(names[:2], " & ")
+		}
+	}
+	if pos.Road.Length-pos.Offset <= intersectionRadius {
+		if names := m.streetNames(pos.To); len(names) > 1 {
+			return strings.Join(names[:2], " & ")
+		}
+	}
+
+	switch {
+	case pos.Road.Name == "":
+		return fmt.Sprintf("%.0f, %.0f", pos.Point.X, pos.Point.Y)
+	case pos.Road.Numbers.IsZero():
+		return pos.Road.Name
+	default:
+		return fmt.Sprintf("%d %s", pos.Road.Numbers.At(pos.Fraction()), pos.Road.Name)
+	}
+}
+
+// AddressAt describes the position on the road closest to p in words
+func (m *Map) AddressAt(p engo.Point) string {
+	return m.Address(m.SnapToRoad(p))
+}
+
+// streetNames returns the names of all streets meeting at the node, sorted by name
+func (m *Map) streetNames(n *RouteNode) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, road := range m.IntersectionRoads(n) {
+		if _, ok := seen[normalizeStreet(road.Name)]; ok || road.Name == "" {
+			continue
+		}
+		seen[normalizeStreet(road.Name)] = struct{}{}
+		names = append(names, road.Name)
+	}
+	sort.Strings(names)
+	return names
+}
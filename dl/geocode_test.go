@@ -0,0 +1,133 @@
+package dl
+
+import (
+	"errors"
+	"testing"
+
+	"engo.io/engo"
+)
+
+// addressMap returns a map with Main Street from (0, 0) to (400, 0), numbered 1 to 41, Station Road from the middle
+// of Main Street to (200, 200), and a road without a name from there to (400, 200)
+func addressMap() *Map {
+	m := &Map{Nodes: []*RouteNode{
+		{ID: 1, Location: engo.Point{X: 0, Y: 0}},
+		{ID: 2, Location: engo.Point{X: 200, Y: 0}},
+		{ID: 3, Location: engo.Point{X: 400, Y: 0}},
+		{ID: 4, Location: engo.Point{X: 200, Y: 200}},
+		{ID: 5, Location: engo.Point{X: 400, Y: 200}},
+	}}
+	m.Nodes[0].Roads = []*Road{{To: 2, Name: "Main Street", Numbers: HouseNumbers{1, 21}}}
+	m.Nodes[1].Roads = []*Road{
+		{To: 3, Name: "Main Street", Numbers: HouseNumbers{21, 41}},
+		{To: 4, Name: "Station Road"},
+	}
+	m.Nodes[3].Roads = []*Road{{To: 5}}
+	m.Initialize()
+	return m
+}
+
+func TestGeocode(t *testing.T) {
+	m := addressMap()
+	tests := []struct {
+		address string
+		point   engo.Point
+		err     error
+	}{
+		{"11 Main Street", engo.Point{X: 100, Y: 0}, nil},
+		{"31 main st.", engo.Point{X: 300, Y: 0}, nil},
+		{"1, Main St", engo.Point{X: 0, Y: 0}, nil},
+		{"100 Main Street", engo.Point{X: 400, Y: 0}, nil},
+		{"Main Street & Station Rd", engo.Point{X: 200, Y: 0}, nil},
+		{"station road & MAIN STREET", engo.Point{X: 200, Y: 0}, nil},
+		{"Station Road", engo.Point{X: 200, Y: 100}, nil},
+		{"Nowhere Lane", engo.Point{}, ErrUnknownAddress},
+		{"5 Nowhere Lane", engo.Point{}, ErrUnknownAddress},
+		{"5 Station Road", engo.Point{}, ErrUnknownAddress},
+		{"Main Street & Nowhere Lane", engo.Point{}, ErrUnknownAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			pos, err := m.Geocode(tt.address)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, expected %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if !pos.OnRoad() || pos.Point.PointDistance(tt.point) > 1e-3 {
+				t.Fatalf("got %v, expected %v", pos.Point, tt.point)
+			}
+		})
+	}
+}
+
+func TestAddress(t *testing.T) {
+	m := addressMap()
+	tests := []struct {
+		p       engo.Point
+		address string
+	}{
+		{engo.Point{X: 100, Y: 5}, "11 Main Street"},
+		{engo.Point{X: 300, Y: -5}, "31 Main Street"},
+		{engo.Point{X: 190, Y: 0}, "Main Street & Station Road"},
+		{engo.Point{X: 200, Y: 100}, "Station Road"},
+		{engo.Point{X: 300, Y: 210}, "300, 200"},
+	}
+
+	for _, tt := range tests {
+		if got := m.AddressAt(tt.p); got != tt.address {
+			t.Errorf("address at %v is %q, expected %q", tt.p, got, tt.address)
+		}
+	}
+}
+
+// TestAddressRoundTrip geocodes the addresses of positions all along the named roads, in both directions
+func TestAddressRoundTrip(t *testing.T) {
+	m := addressMap()
+	for _, n := range m.Nodes {
+		for _, road := range n.Roads {
+			if road.Name == "" {
+				continue
+			}
+			seg := RoadSegment{n, m.Node(road.To), road}
+			for offset := float32(0); offset <= road.Length; offset += 7 {
+				pos := m.PositionAt(seg, offset)
+				address := m.Address(pos)
+				back, err := m.Geocode(address)
+				if err != nil {
+					t.Fatal(address, err)
+				}
+				// Without house numbers, the middle of the street is used
+				tolerance := float32(intersectionRadius)
+				if road.Numbers.IsZero() {
+					tolerance = road.Length / 2
+				}
+				if d := back.Point.PointDistance(pos.Point); d > tolerance {
+					t.Fatalf("%v is at %q, which is at %v", pos.Point, address, back.Point)
+				}
+			}
+		}
+	}
+}
+
+func TestHouseNumbers(t *testing.T) {
+	numbers := HouseNumbers{1, 21}
+	for _, tt := range []struct {
+		fraction float32
+		number   int
+	}{{0, 1}, {0.5, 11}, {1, 21}, {0.52, 11}, {0.53, 12}} {
+		if got := numbers.At(tt.fraction); got != tt.number {
+			t.Errorf("number at %g is %d, expected %d", tt.fraction, got, tt.number)
+		}
+		if got := numbers.reversed().At(1 - tt.fraction); got != tt.number {
+			t.Errorf("reversed number at %g is %d, expected %d", 1-tt.fraction, got, tt.number)
+		}
+	}
+
+	first, second := numbers.split(0.25)
+	if first != (HouseNumbers{1, 6}) || second != (HouseNumbers{6, 21}) {
+		t.Fatal(first, second)
+	}
+}
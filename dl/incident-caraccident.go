@@ -0,0 +1,39 @@
+package dl
+
+import "engo.io/engo"
+
+// IncidentCarAccident is a crash which closes the road until the injured have been taken care of and the wrecks have
+// been towed away
+type IncidentCarAccident struct {
+	Location *engo.Point
+}
+
+func (IncidentCarAccident) Type() string {
+	return "IncidentCarAccident"
+}
+
+func (IncidentCarAccident) Penalty() int {
+	return 20
+}
+
+func (IncidentCarAccident) Reward() int {
+	return 50
+}
+
+func (IncidentCarAccident) Requires() Capabilities {
+	return Capabilities{CapabilityMedical, CapabilityTowing}
+}
+
+func (IncidentCarAccident) RoadSpeedFactor() float32 {
+	return 0
+}
+
+func (i *IncidentCarAccident) SetLocation(loc *engo.Point) {
+	i.Location = loc
+}
+
+func (i *IncidentCarAccident) Update(dt float32) {}
+
+func (IncidentCarAccident) Resolved() int {
+	return 0
+}
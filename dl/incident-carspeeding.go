@@ -3,10 +3,14 @@ package dl
 import (
 	"engo.io/engo"
 	"fmt"
+	"log"
 
 	"github.com/luxengine/math"
 )
 
+// carSpeedingSpeed is the speed of a speeding car, which ignores any speed limits
+const carSpeedingSpeed = 250
+
 type IncidentCarSpeeding struct {
 	Start engo.Point
 	Goal  engo.Point
@@ -44,6 +48,9 @@ var (
 			func(curr, goal, pos *RouteNode) float32 {
 				d := float32(math.MaxFloat32)
 				for _, cop := range police {
+					if cop.Unit.Agency != AgencyPolice {
+						continue
+					}
 					dx := pos.Location.X - cop.Location.X
 					dy := pos.Location.Y - cop.Location.Y
 					if d2 := dx*dx + dy*dy; d2 < d {
@@ -88,20 +95,53 @@ func (IncidentCarSpeeding) Reward() int {
 	return 100
 }
 
+func (IncidentCarSpeeding) Suspects() int {
+	return 1
+}
+
+func (IncidentCarSpeeding) Requires() Capabilities {
+	return Capabilities{CapabilityArrest}
+}
+
 func (i *IncidentCarSpeeding) SetLocation(loc *engo.Point) {
 	i.Location = loc
 }
 
 func (i *IncidentCarSpeeding) Update(dt float32) {
+	if CurrentMap == nil {
+		return
+	}
+
 	// Compute route if required
-	if len(i.currentRoute.Nodes) < 1 {
-		i.currentRoute = SetRoute(*i.Location, i.Goal, func(curr, goal, pos *RouteNode) float32 {
-			return criminalMind.Value(curr, goal, pos)
-		})
+	var (
+		route Route
+		err   error
+	)
+	switch {
+	case len(i.currentRoute.Nodes) < 1:
+		route, err = SetRoute(*i.Location, i.Goal, TravelTimeCost(carSpeedingSpeed), i.heuristic())
+	case CurrentMap.NeedsReplanning(&i.currentRoute):
+		route, err = CurrentMap.Replan(i.currentRoute, TravelTimeCost(carSpeedingSpeed), i.heuristic())
+	default:
+		route = i.currentRoute
+	}
+	if err != nil {
+		log.Println("Speeding car is unable to reach its goal:", err)
+		i.finished = true
+		return
 	}
+	i.currentRoute = route
 	i.Move(dt)
 }
 
+// heuristic lets the criminal mind decide where to go, from where the car is now
+func (i *IncidentCarSpeeding) heuristic() Heuristic {
+	start := CurrentMap.NearestNode(*i.Location)
+	return func(pos, goal *RouteNode) float32 {
+		return criminalMind.Value(start, goal, pos)
+	}
+}
+
 func (i IncidentCarSpeeding) Resolved() int {
 	if i.finished {
 		return -1
@@ -110,8 +150,11 @@ func (i IncidentCarSpeeding) Resolved() int {
 }
 
 func (i *IncidentCarSpeeding) Move(dt float32) {
-	const speed = 250
-
+	// Even those ignoring the speed limits are stuck in traffic
+	speed := float32(carSpeedingSpeed)
+	if road := i.currentRoute.Roads[0]; road != nil {
+		speed *= road.Traffic()
+	}
 	var distance = speed / 3.6 * dt
 
 	target := i.currentRoute.Nodes[0].Location
@@ -129,6 +172,7 @@ func (i *IncidentCarSpeeding) Move(dt float32) {
 		movementX = dx
 		movementY = dy
 		i.currentRoute.Nodes = i.currentRoute.Nodes[1:]
+		i.currentRoute.Roads = i.currentRoute.Roads[1:]
 		if len(i.currentRoute.Nodes) == 0 {
 			i.finished = true
 		} else {
@@ -0,0 +1,39 @@
+package dl
+
+import "engo.io/engo"
+
+// IncidentFire is a building on fire, which blocks part of the road until the fire is out and the injured have been
+// taken care of
+type IncidentFire struct {
+	Location *engo.Point
+}
+
+func (IncidentFire) Type() string {
+	return "IncidentFire"
+}
+
+func (IncidentFire) Penalty() int {
+	return 100
+}
+
+func (IncidentFire) Reward() int {
+	return 200
+}
+
+func (IncidentFire) Requires() Capabilities {
+	return Capabilities{CapabilityFire, CapabilityMedical}
+}
+
+func (IncidentFire) RoadSpeedFactor() float32 {
+	return 0.5
+}
+
+func (i *IncidentFire) SetLocation(loc *engo.Point) {
+	i.Location = loc
+}
+
+func (i *IncidentFire) Update(dt float32) {}
+
+func (IncidentFire) Resolved() int {
+	return 0
+}
@@ -0,0 +1,38 @@
+package dl
+
+import "engo.io/engo"
+
+// IncidentRobbery is a hold-up, usually at a bank, whose robber is taken to jail once the police gets there
+type IncidentRobbery struct {
+	Location *engo.Point
+}
+
+func (IncidentRobbery) Type() string {
+	return "IncidentRobbery"
+}
+
+func (IncidentRobbery) Penalty() int {
+	return 50
+}
+
+func (IncidentRobbery) Reward() int {
+	return 150
+}
+
+func (IncidentRobbery) Suspects() int {
+	return 1
+}
+
+func (IncidentRobbery) Requires() Capabilities {
+	return Capabilities{CapabilityArrest}
+}
+
+func (i *IncidentRobbery) SetLocation(loc *engo.Point) {
+	i.Location = loc
+}
+
+func (i *IncidentRobbery) Update(dt float32) {}
+
+func (IncidentRobbery) Resolved() int {
+	return 0
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"image/color"
 	"log"
+	"math/rand"
+	"time"
 
 	"engo.io/ecs"
 	"engo.io/engo"
@@ -32,6 +34,17 @@ type Incident interface {
 	Penalty() int
 }
 
+// RoadBlocker is an incident which closes or slows down the roads at its location until it's resolved
+type RoadBlocker interface {
+	// RoadSpeedFactor is multiplied with the speed on the roads; 0 closes them
+	RoadSpeedFactor() float32
+}
+
+// Arrestable is an incident with suspects, who are arrested by the unit resolving it
+type Arrestable interface {
+	Suspects() int
+}
+
 type IncidentDebugViewMessage struct {
 	NewValue bool
 }
@@ -46,6 +59,7 @@ func (IncidentNewMessage) Type() string { return "IncidentNewMessage" }
 
 type IncidentDebugSystem struct {
 	world *ecs.World
+	rng   *rand.Rand
 }
 
 type IncidentResolveMessage struct {
@@ -57,6 +71,7 @@ func (IncidentResolveMessage) Type() string { return "IncidentResolveMessage" }
 
 func (d *IncidentDebugSystem) New(w *ecs.World) {
 	d.world = w
+	d.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 	engo.Input.RegisterButton(incidentSpawningKey, engo.F1)
 	engo.Input.RegisterButton(incidentViewKey, engo.F2)
 }
@@ -67,6 +82,11 @@ func (d *IncidentDebugSystem) Update(dt float32) {
 	if engo.Input.Button(incidentSpawningKey).JustPressed() {
 		// Spawn!
 		log.Println("Spawn!")
+		if CurrentMap != nil {
+			incident := []Incident{&IncidentCarAccident{}, &IncidentRobbery{}, &IncidentFire{}}[d.rng.Intn(3)]
+			location := CurrentMap.RandomIncidentLocationFor(incident.Type(), d.rng)
+			engo.Mailbox.Dispatch(IncidentNewMessage{IncidentComponent{Location: &location, Incident: incident}})
+		}
 	}
 
 	if engo.Input.Button(incidentViewKey).JustPressed() {
@@ -90,6 +110,8 @@ type IncidentComponent struct {
 	Incident Incident
 
 	Reports []IncidentReportComponent
+
+	restriction *RoadRestriction
 }
 
 type IncidentReportComponent struct {
@@ -119,6 +141,9 @@ type IncidentReportEntity struct {
 type IncidentSystem struct {
 	world         *ecs.World
 	incidentLabel ui.Label
+	// tooltip describes the report the mouse is over
+	tooltip           ui.Label
+	tooltipBackground ui.Graphic
 
 	activeIncidents       []*IncidentEntity
 	activeIncidentReports map[uint64][]*IncidentReportEntity
@@ -167,10 +192,42 @@ func (d *IncidentSystem) New(w *ecs.World) {
 	d.incidentLabel.SetText(fmt.Sprintf("Active Incidents: %d", len(d.activeIncidents)))
 	d.incidentLabel.RenderComponent.SetShader(common.HUDShader)
 
+	tooltipFnt := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.Black,
+		Size: float64(ui.TooltipLineHeight),
+	}
+	if err := tooltipFnt.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+
+	d.tooltip = ui.Label{
+		BasicEntity:     ecs.NewBasic(),
+		Font:            tooltipFnt,
+		SpaceComponent:  common.SpaceComponent{Width: 300, Height: ui.TooltipLineHeight},
+		RenderComponent: common.RenderComponent{Hidden: true},
+	}
+	d.tooltip.SetText(" ")
+	d.tooltip.SetZIndex(10)
+	d.tooltip.RenderComponent.SetShader(common.TextHUDShader)
+	d.tooltipBackground = ui.Graphic{
+		BasicEntity: ecs.NewBasic(),
+		RenderComponent: common.RenderComponent{
+			Drawable: ui.TooltipGraphic,
+			Color:    ui.TooltipColor,
+			Hidden:   true,
+		},
+		SpaceComponent: common.SpaceComponent{Width: 300, Height: ui.TooltipLineHeight},
+	}
+	d.tooltipBackground.SetZIndex(9)
+	d.tooltipBackground.RenderComponent.SetShader(common.HUDShader)
+
 	for _, system := range d.world.Systems() {
 		switch sys := system.(type) {
 		case *common.RenderSystem:
 			sys.Add(&d.incidentLabel.BasicEntity, &d.incidentLabel.RenderComponent, &d.incidentLabel.SpaceComponent)
+			sys.Add(&d.tooltip.BasicEntity, &d.tooltip.RenderComponent, &d.tooltip.SpaceComponent)
+			sys.Add(&d.tooltipBackground.BasicEntity, &d.tooltipBackground.RenderComponent, &d.tooltipBackground.SpaceComponent)
 		}
 	}
 }
@@ -215,6 +272,7 @@ func (d *IncidentSystem) Remove(b ecs.BasicEntity) {
 
 func (d *IncidentSystem) Update(dt float32) {
 	d.incidentLabel.SetText(fmt.Sprintf("Active Incidents: %d", len(d.activeIncidents)))
+	d.updateTooltip()
 
 	// Manage all incidents
 	var msgs []IncidentResolveMessage
@@ -230,6 +288,9 @@ func (d *IncidentSystem) Update(dt float32) {
 		default:
 			continue
 		}
+		if CurrentMap != nil {
+			CurrentMap.countIncident(*i.Location, f)
+		}
 
 		msgs = append(msgs, IncidentResolveMessage{&i.IncidentComponent, &i.BasicEntity})
 	}
@@ -240,7 +301,42 @@ func (d *IncidentSystem) Update(dt float32) {
 	}
 }
 
+// updateTooltip shows where the report the mouse is over came from, and what it's about
+func (d *IncidentSystem) updateTooltip() {
+	for _, reports := range d.activeIncidentReports {
+		for _, report := range reports {
+			switch {
+			case report.MouseComponent.Enter:
+				report.Color = ui.IncidentReportColorHover
+				d.tooltip.SetText(report.Description())
+				// Using raw location because it's a HUD
+				d.tooltipBackground.Position = engo.Point{engo.Input.Mouse.X + ui.IncidentReportSize, engo.Input.Mouse.Y}
+				d.tooltip.Position = d.tooltipBackground.Position
+				d.tooltip.Hidden, d.tooltipBackground.Hidden = false, false
+			case report.MouseComponent.Leave:
+				report.Color = ui.IncidentReportColor
+				d.tooltip.Hidden, d.tooltipBackground.Hidden = true, true
+			}
+		}
+	}
+}
+
+// Description describes what the report is about, and where it came from
+func (r *IncidentReportComponent) Description() string {
+	if CurrentMap == nil {
+		return incidentName(r.Type)
+	}
+	return fmt.Sprintf("%s at %s", incidentName(r.Type), CurrentMap.AddressAt(*r.Location))
+}
+
 func (d *IncidentSystem) Spawn(in IncidentComponent) {
+	location := *in.Location
+	if CurrentMap != nil {
+		// Incidents happen on the road
+		location = CurrentMap.SnapToRoad(location).Point
+		CurrentMap.countIncident(location, 0)
+	}
+
 	ie := &IncidentEntity{
 		BasicEntity: ecs.NewBasic(),
 		RenderComponent: common.RenderComponent{
@@ -250,7 +346,7 @@ func (d *IncidentSystem) Spawn(in IncidentComponent) {
 			TextureAlignment: common.AlignCenter,
 		},
 		SpaceComponent: common.SpaceComponent{
-			Position: *in.Location,
+			Position: location,
 			Width:    ui.IncidentSize,
 			Height:   ui.IncidentSize,
 			Rotation: 0,
@@ -258,6 +354,10 @@ func (d *IncidentSystem) Spawn(in IncidentComponent) {
 	}
 	in.Location = &ie.SpaceComponent.Position
 	in.Incident.SetLocation(&ie.SpaceComponent.Position)
+	if blocker, ok := in.Incident.(RoadBlocker); ok && CurrentMap != nil {
+		roads := CurrentMap.RoadsAt(CurrentMap.SnapToRoad(location))
+		in.restriction = CurrentMap.Restrict(blocker.RoadSpeedFactor(), roads...)
+	}
 	ie.IncidentComponent = in
 
 	ie.RenderComponent.SetZIndex(5)
@@ -278,8 +378,9 @@ func (d *IncidentSystem) Spawn(in IncidentComponent) {
 			},
 			IncidentReportComponent: report,
 		}
-		report.Location = &re.SpaceComponent.Position
+		re.IncidentReportComponent.Location = &re.SpaceComponent.Position
 		re.RenderComponent.SetZIndex(5)
+		Radio("Reported: %s", re.Description())
 
 		var curList []*IncidentReportEntity
 		if l, ok := d.activeIncidentReports[ie.ID()]; ok {
@@ -314,7 +415,11 @@ func (d *IncidentSystem) Spawn(in IncidentComponent) {
 }
 
 func (d *IncidentSystem) Resolve(in *IncidentComponent, basic *ecs.BasicEntity) {
-	log.Println("Resolving", in.Location)
+	if CurrentMap != nil {
+		Radio("%s resolved at %s", incidentName(in.Incident.Type()), CurrentMap.AddressAt(*in.Location))
+		CurrentMap.LiftRestriction(in.restriction)
+		in.restriction = nil
+	}
 	d.world.RemoveEntity(*basic)
 	// TODO: "award" or "penalty"
 }
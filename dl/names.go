@@ -0,0 +1,57 @@
+package dl
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// nameTable holds the names of the values of an enum, like the road classes, by which they are written in files
+type nameTable[T ~uint8] struct {
+	// typeName is used for values without a name, like RoadClass(7)
+	typeName string
+	// what describes the values in errors, like "unknown road class"
+	what  string
+	names map[T]string
+}
+
+func (t nameTable[T]) name(v T) string {
+	if name, ok := t.names[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s(%d)", t.typeName, v)
+}
+
+func (t nameTable[T]) parse(s string) (T, error) {
+	for v, name := range t.names {
+		if name == s {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s: %q", t.what, s)
+}
+
+// unmarshalYAML reads a value by its name. An unknown name is reported as a type error instead of a plain one: this
+// allows the parser to continue, and report any other problems as well.
+func (t nameTable[T]) unmarshalYAML(unmarshal func(interface{}) error, v *T) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := t.parse(s)
+	if err != nil {
+		return &yaml.TypeError{Errors: []string{err.Error()}}
+	}
+	*v = parsed
+	return nil
+}
+
+// unmarshalText reads a value by its name, for the JSON and TOML unit files
+func (t nameTable[T]) unmarshalText(b []byte, v *T) error {
+	parsed, err := t.parse(string(b))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
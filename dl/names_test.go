@@ -0,0 +1,40 @@
+package dl
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestNameTable(t *testing.T) {
+	for class, name := range roadClassNames.names {
+		if parsed, err := ParseRoadClass(name); err != nil || parsed != class {
+			t.Fatalf("%q was parsed as %v (%v), expected %v", name, parsed, err, class)
+		}
+	}
+	if name := RoadClass(9).String(); name != "RoadClass(9)" {
+		t.Fatalf("a road class without a name is %q", name)
+	}
+	if _, err := ParsePOIKind("castle"); err == nil || err.Error() != `unknown point of interest kind: "castle"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every unknown name is reported, not just the first
+	var kinds struct {
+		Zones []ZoneKind `yaml:"zones"`
+		POIs  []POIKind  `yaml:"pois"`
+	}
+	err := yaml.Unmarshal([]byte("zones: [beat, district]\npois: [bar, castle]\n"), &kinds)
+	if err == nil || !strings.Contains(err.Error(), "district") || !strings.Contains(err.Error(), "castle") {
+		t.Fatalf("not every unknown name was reported: %v", err)
+	}
+	if len(kinds.Zones) == 0 || kinds.Zones[0] != ZoneBeat || len(kinds.POIs) == 0 || kinds.POIs[0] != POIBar {
+		t.Fatalf("the known names weren't read: %v", kinds)
+	}
+
+	var agency Agency
+	if err := agency.UnmarshalText([]byte("fire")); err != nil || agency != AgencyFire {
+		t.Fatalf("read %v (%v), expected fire", agency, err)
+	}
+}
@@ -0,0 +1,436 @@
+package dl
+
+import (
+	"fmt"
+	"image/color"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+)
+
+// orderPanelLines is the number of orders the order panel shows, including the current one
+const orderPanelLines = 6
+
+const (
+	orderPanelTop         float32 = 136
+	orderPanelWidth       float32 = 280
+	orderPanelButtonWidth float32 = 70
+)
+
+var commandNames = map[PoliceCommand]string{
+	CommandHold:           "Hold",
+	CommandMove:           "Move",
+	CommandLookout:        "Hold watch",
+	CommandSearchArea:     "Search area",
+	CommandTrafficControl: "Close road",
+	CommandPursue:         "Pursue",
+	CommandPatrol:         "Patrol zone",
+	CommandTransport:      "Transport suspects",
+}
+
+func (c PoliceCommand) String() string {
+	if name, ok := commandNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("PoliceCommand(%d)", c)
+}
+
+var lastOrderID uint64
+
+// Order is a command given to a unit, and where to carry it out. The node at the target, if any, should have been
+// acquired using Map.AcquireNode, and is released once the order has been carried out or has been cancelled.
+type Order struct {
+	ID      uint64
+	Command PoliceCommand
+	Target  engo.Point
+	Node    *RouteNode
+}
+
+// NewOrder returns an order with a new ID
+func NewOrder(c PoliceCommand, target engo.Point, node *RouteNode) Order {
+	lastOrderID++
+	return Order{ID: lastOrderID, Command: c, Target: target, Node: node}
+}
+
+// Description describes the order in words, like "Hold watch at 12 Main Street"
+func (o Order) Description() string {
+	if o.Command == CommandHold || CurrentMap == nil {
+		return o.Command.String()
+	}
+	return fmt.Sprintf("%s at %s", o.Command, CurrentMap.AddressAt(o.Target))
+}
+
+// QueueOrder adds the order to the end of the queue, and returns its ID
+func (p *PoliceComponent) QueueOrder(o Order) uint64 {
+	p.Orders = append(p.Orders, o)
+	return o.ID
+}
+
+// InsertOrder adds the order to the front of the queue, to be carried out once the current order is done
+func (p *PoliceComponent) InsertOrder(o Order) uint64 {
+	p.Orders = append([]Order{o}, p.Orders...)
+	return o.ID
+}
+
+// PreemptOrder interrupts the current order to carry out this one right away. The current order is carried out
+// again afterwards, before any queued orders.
+func (p *PoliceComponent) PreemptOrder(o Order) uint64 {
+	if p.Current.Command != CommandHold {
+		p.suspendOrder()
+		p.suspended = append(p.suspended, p.Current)
+	} else {
+		p.finishOrder()
+	}
+	p.Current = o
+	return o.ID
+}
+
+// PrioritizeOrder moves the waiting order to the front of the queue, or makes it the first interrupted order to be
+// carried out again. It returns false if there's no such order.
+func (p *PoliceComponent) PrioritizeOrder(id uint64) bool {
+	for i, o := range p.suspended {
+		if o.ID == id {
+			copy(p.suspended[i:], p.suspended[i+1:])
+			p.suspended[len(p.suspended)-1] = o
+			return true
+		}
+	}
+	for i, o := range p.Orders {
+		if o.ID == id {
+			copy(p.Orders[1:i+1], p.Orders[:i])
+			p.Orders[0] = o
+			return true
+		}
+	}
+	return false
+}
+
+// CancelOrder cancels the order, whether it's being carried out, interrupted or queued. It returns false if there's
+// no such order, or if it's bringing suspects to jail: those have to be dropped off.
+func (p *PoliceComponent) CancelOrder(id uint64) bool {
+	if p.Current.ID == id && p.Current.Command != CommandHold {
+		if !p.Current.cancellable(p) {
+			return false
+		}
+		p.finishOrder()
+		p.Current = Order{}
+		p.CurrentRoute = Route{}
+		return true
+	}
+	for _, orders := range []*[]Order{&p.suspended, &p.Orders} {
+		for i, o := range *orders {
+			if o.ID == id {
+				if !o.cancellable(p) {
+					return false
+				}
+				CurrentMap.ReleaseNode(o.Node)
+				*orders = append((*orders)[:i], (*orders)[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClearOrders cancels every interrupted and queued order, but not the current one, and not those bringing suspects
+// to jail
+func (p *PoliceComponent) ClearOrders() {
+	p.suspended = p.clearOrders(p.suspended)
+	p.Orders = p.clearOrders(p.Orders)
+}
+
+// clearOrders cancels the orders, and returns those which can't be cancelled
+func (p *PoliceComponent) clearOrders(orders []Order) []Order {
+	var kept []Order
+	for _, o := range orders {
+		if !o.cancellable(p) {
+			kept = append(kept, o)
+			continue
+		}
+		CurrentMap.ReleaseNode(o.Node)
+	}
+	return kept
+}
+
+// CancelOrders cancels the current order and every other order, including those bringing suspects to jail
+func (p *PoliceComponent) CancelOrders() {
+	for _, o := range append(p.suspended, p.Orders...) {
+		CurrentMap.ReleaseNode(o.Node)
+	}
+	p.suspended, p.Orders = nil, nil
+	p.finishOrder()
+	p.Current = Order{}
+	p.CurrentRoute = Route{}
+}
+
+// cancellable indicates whether or not the order of the unit can be cancelled by the dispatcher. Suspects have to be
+// brought to jail.
+func (o Order) cancellable(p *PoliceComponent) bool {
+	return o.Command != CommandTransport || p.Arrested == 0
+}
+
+// waiting returns the orders which are carried out once the current one is done: the interrupted ones first, the
+// last interrupted one first, and then the queue
+func (p *PoliceComponent) waiting() []Order {
+	orders := make([]Order, 0, len(p.suspended)+len(p.Orders))
+	for i := len(p.suspended) - 1; i >= 0; i-- {
+		orders = append(orders, p.suspended[i])
+	}
+	return append(orders, p.Orders...)
+}
+
+// givesWay indicates whether or not an open-ended order, like holding watch, should stop for an order which was
+// given after it
+func (p *PoliceComponent) givesWay() bool {
+	for _, o := range p.Orders {
+		if o.ID > p.Current.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// suspendOrder stops carrying out the current order, releasing whatever it was using except its node, so it can be
+// carried out again later
+func (p *PoliceComponent) suspendOrder() {
+	if p.closure != nil {
+		CurrentMap.LiftRestriction(p.closure)
+		p.closure = nil
+	}
+	if p.pursuit != nil {
+		p.pursued, p.pursuit = DispatchSystemIncidentEntity{}, nil
+	}
+	// The route leads to wherever the order was taking the unit
+	p.CurrentRoute = Route{}
+	p.searchTime = 0
+}
+
+// finishOrder releases whatever the current order was using
+func (p *PoliceComponent) finishOrder() {
+	if p.closure != nil {
+		CurrentMap.LiftRestriction(p.closure)
+		p.closure = nil
+	}
+	if p.pursuit != nil {
+		// The route leads to wherever the incident was last seen
+		p.CurrentRoute = Route{}
+		p.pursued, p.pursuit = DispatchSystemIncidentEntity{}, nil
+	}
+	if p.Current.Node != nil {
+		CurrentMap.ReleaseNode(p.Current.Node)
+		p.Current.Node = nil
+	}
+	p.searchTime = 0
+}
+
+// nextOrder finishes the current order, and returns the next one: the last interrupted order, or the first one from
+// the queue
+func (p *PoliceComponent) nextOrder() Order {
+	p.finishOrder()
+	if n := len(p.suspended); n > 0 {
+		o := p.suspended[n-1]
+		p.suspended = p.suspended[:n-1]
+		return o
+	}
+	if len(p.Orders) == 0 {
+		return Order{}
+	}
+
+	o := p.Orders[0]
+	p.Orders = p.Orders[1:]
+	return o
+}
+
+// orderPlacement is where the dispatcher puts new orders of the selected unit
+type orderPlacement uint8
+
+const (
+	placeLast orderPlacement = iota
+	placeFirst
+	placeUrgent
+)
+
+var orderPlacementNames = map[orderPlacement]string{
+	placeLast:   "New orders: last",
+	placeFirst:  "New orders: first",
+	placeUrgent: "New orders: urgent",
+}
+
+// orderPanel lists the orders of the selected unit, with buttons to cancel them or to carry them out first
+type orderPanel struct {
+	placement orderPlacement
+	// ids are the IDs of the orders on the lines
+	ids []uint64
+
+	placementButton *ui.Button
+	clearButton     *ui.Button
+	lines           []*ui.Button
+	cancelButtons   []*ui.Button
+	firstButtons    []*ui.Button
+}
+
+func (o *orderPanel) New(w *ecs.World) {
+	fnt := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.Black,
+		Size: float64(ui.TooltipLineHeight),
+	}
+	if err := fnt.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+
+	o.ids = make([]uint64, orderPanelLines)
+	o.placementButton = newPanelButton(fnt, orderPlacementNames[o.placement], 4, orderPanelTop, orderPanelWidth)
+	o.clearButton = newPanelButton(fnt, "Clear", 4+orderPanelWidth, orderPanelTop, 2*orderPanelButtonWidth)
+	for i := 0; i < orderPanelLines; i++ {
+		y := orderPanelTop + float32(i+1)*ui.TooltipLineHeight
+		o.lines = append(o.lines, newPanelButton(fnt, " ", 4, y, orderPanelWidth))
+		o.cancelButtons = append(o.cancelButtons, newPanelButton(fnt, "Cancel", 4+orderPanelWidth, y, orderPanelButtonWidth))
+		o.firstButtons = append(o.firstButtons, newPanelButton(fnt, "First", 4+orderPanelWidth+orderPanelButtonWidth, y, orderPanelButtonWidth))
+	}
+
+	for _, system := range w.Systems() {
+		switch sys := system.(type) {
+		case *common.RenderSystem:
+			for _, b := range o.all() {
+				sys.Add(&b.Label.BasicEntity, &b.Label.RenderComponent, &b.Label.SpaceComponent)
+				sys.Add(&b.Graphic.BasicEntity, &b.Graphic.RenderComponent, &b.Graphic.SpaceComponent)
+			}
+		case *common.MouseSystem:
+			for _, b := range o.buttons() {
+				sys.Add(&b.Graphic.BasicEntity, &b.MouseComponent, &b.Graphic.SpaceComponent, &b.Graphic.RenderComponent)
+			}
+		}
+	}
+	o.hide()
+}
+
+// newPanelButton creates a button of the order panel, at a fixed position on the screen
+func newPanelButton(fnt *common.Font, label string, x, y, width float32) *ui.Button {
+	but := ui.NewButton(fnt, label)
+	but.OnMouseOver = func(b *ui.Button) {
+		b.Graphic.Color = ui.TooltipColorHover
+		ui.StartHovering(b.Graphic.ID())
+	}
+	but.OnMouseOut = func(b *ui.Button) {
+		b.Graphic.Color = ui.TooltipColor
+		ui.StopHovering(b.Graphic.ID())
+	}
+	but.Label.Position = engo.Point{x + 4, y}
+	but.Label.Width = width
+	but.Label.Height = ui.TooltipLineHeight
+	but.Label.Scale = engo.Point{0.7, 0.7}
+	but.Label.SetZIndex(10)
+	but.Label.SetShader(common.TextHUDShader)
+	but.Graphic.Color = ui.TooltipColor
+	but.Graphic.Drawable = ui.TooltipGraphic
+	but.Graphic.Position = engo.Point{x, y}
+	but.Graphic.Width = width
+	but.Graphic.Height = ui.TooltipLineHeight
+	but.Graphic.SetZIndex(9)
+	but.Graphic.RenderComponent.SetShader(common.HUDShader)
+	return but
+}
+
+// all returns every part of the panel, including the lines
+func (o *orderPanel) all() []*ui.Button {
+	return append(o.lines, o.buttons()...)
+}
+
+// buttons returns the parts of the panel which can be clicked
+func (o *orderPanel) buttons() []*ui.Button {
+	buttons := []*ui.Button{o.placementButton, o.clearButton}
+	buttons = append(buttons, o.cancelButtons...)
+	return append(buttons, o.firstButtons...)
+}
+
+func (o *orderPanel) hide() {
+	for _, b := range o.all() {
+		b.Label.Hidden, b.Graphic.Hidden = true, true
+		ui.StopHovering(b.Graphic.ID())
+	}
+}
+
+// Update handles the buttons of the panel, and shows the current orders of the unit. It returns true if a button was
+// clicked.
+func (o *orderPanel) Update(p *PoliceComponent) bool {
+	var used bool
+	for _, b := range o.buttons() {
+		if b.Enter {
+			b.OnMouseOver(b)
+		} else if b.Leave {
+			b.OnMouseOut(b)
+		}
+	}
+	switch {
+	case o.placementButton.Clicked:
+		o.placement = (o.placement + 1) % (placeUrgent + 1)
+		o.placementButton.Label.SetText(orderPlacementNames[o.placement])
+		used = true
+	case o.clearButton.Clicked:
+		p.ClearOrders()
+		used = true
+	}
+	for i := range o.lines {
+		switch {
+		case o.cancelButtons[i].Clicked && o.ids[i] != 0:
+			p.CancelOrder(o.ids[i])
+			used = true
+		case o.firstButtons[i].Clicked && o.ids[i] != 0:
+			p.PrioritizeOrder(o.ids[i])
+			used = true
+		}
+	}
+
+	orders := p.waiting()
+	if p.Current.Command != CommandHold {
+		orders = append([]Order{p.Current}, orders...)
+	}
+	o.placementButton.Label.Hidden, o.placementButton.Graphic.Hidden = false, false
+	o.clearButton.Label.Hidden, o.clearButton.Graphic.Hidden = false, false
+	for i, line := range o.lines {
+		o.ids[i] = 0
+		visible := i < len(orders)
+		if visible {
+			o.ids[i] = orders[i].ID
+			text := fmt.Sprintf("%d. %s", i+1, orders[i].Description())
+			if orders[i].ID == p.Current.ID {
+				text = "Now: " + orders[i].Description()
+			}
+			line.Label.SetText(text)
+		} else if i == 0 {
+			line.Label.SetText("No orders")
+			visible = true
+		}
+		line.Label.Hidden, line.Graphic.Hidden = !visible, !visible
+
+		hasOrder := o.ids[i] != 0
+		cancel := hasOrder && orders[i].cancellable(p)
+		o.cancelButtons[i].Label.Hidden, o.cancelButtons[i].Graphic.Hidden = !cancel, !cancel
+		// The current order is carried out first already
+		first := hasOrder && o.ids[i] != p.Current.ID
+		o.firstButtons[i].Label.Hidden, o.firstButtons[i].Graphic.Hidden = !first, !first
+	}
+	return used
+}
+
+// place gives the order to the unit, according to where the dispatcher wants new orders to go
+func (o *orderPanel) place(p *PoliceComponent, orders ...Order) {
+	if o.placement == placeLast {
+		for _, order := range orders {
+			p.QueueOrder(order)
+		}
+		return
+	}
+
+	// Going backwards keeps the orders in the same order at the front
+	for i := len(orders) - 1; i >= 0; i-- {
+		if o.placement == placeUrgent {
+			p.PreemptOrder(orders[i])
+		} else {
+			p.InsertOrder(orders[i])
+		}
+	}
+}
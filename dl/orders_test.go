@@ -0,0 +1,123 @@
+package dl
+
+import (
+	"reflect"
+	"testing"
+
+	"engo.io/engo"
+)
+
+func commandsOf(orders []Order) []PoliceCommand {
+	var commands []PoliceCommand
+	for _, o := range orders {
+		commands = append(commands, o.Command)
+	}
+	return commands
+}
+
+func TestOrderQueue(t *testing.T) {
+	p := &PoliceComponent{Unit: UnitType{Name: "Car"}}
+	p.QueueOrder(NewOrder(CommandMove, engo.Point{}, nil))
+	b := p.QueueOrder(NewOrder(CommandLookout, engo.Point{}, nil))
+	c := p.InsertOrder(NewOrder(CommandPatrol, engo.Point{}, nil))
+	if p.Current = p.nextOrder(); p.Current.ID != c || len(p.Orders) != 2 {
+		t.Fatal(p.Current, p.Orders)
+	}
+
+	var panel orderPanel
+	panel.placement = placeFirst
+	panel.place(p, NewOrder(CommandPursue, engo.Point{}, nil), NewOrder(CommandSearchArea, engo.Point{}, nil))
+	want := []PoliceCommand{CommandPursue, CommandSearchArea, CommandMove, CommandLookout}
+	if got := commandsOf(p.Orders); !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+
+	if !p.PrioritizeOrder(b) || p.Orders[0].ID != b || len(p.Orders) != 4 {
+		t.Fatal(commandsOf(p.Orders))
+	}
+	if !p.CancelOrder(b) || p.CancelOrder(b) || len(p.Orders) != 3 {
+		t.Fatal(commandsOf(p.Orders))
+	}
+	if !p.CancelOrder(c) || p.Current.Command != CommandHold {
+		t.Fatal(p.Current)
+	}
+	p.Current = p.nextOrder()
+	p.ClearOrders()
+	if len(p.Orders) != 0 || p.Current.Command != CommandPursue {
+		t.Fatal(p.Current, p.Orders)
+	}
+}
+
+func TestUrgentOrders(t *testing.T) {
+	p := &PoliceComponent{Unit: UnitType{Name: "Car"}}
+	p.QueueOrder(NewOrder(CommandPatrol, engo.Point{}, nil))
+	p.QueueOrder(NewOrder(CommandMove, engo.Point{}, nil))
+	p.Current = p.nextOrder()
+
+	var panel orderPanel
+	panel.placement = placeUrgent
+	panel.place(p, NewOrder(CommandMove, engo.Point{}, nil), NewOrder(CommandLookout, engo.Point{}, nil))
+	if p.Current.Command != CommandMove || len(p.Orders) != 1 {
+		t.Fatal(p.Current, p.Orders)
+	}
+	want := []PoliceCommand{CommandLookout, CommandPatrol, CommandMove}
+	if got := commandsOf(p.waiting()); !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+
+	// The urgent lookout doesn't give way to the orders queued before it, and the patrol continues afterwards
+	if p.Current = p.nextOrder(); p.Current.Command != CommandLookout || p.givesWay() {
+		t.Fatal(p.Current)
+	}
+	if p.Current = p.nextOrder(); p.Current.Command != CommandPatrol {
+		t.Fatal(p.Current)
+	}
+	p.QueueOrder(NewOrder(CommandSearchArea, engo.Point{}, nil))
+	if !p.givesWay() {
+		t.Fatal("the patrol doesn't give way to a new order")
+	}
+}
+
+func TestPreemptHeldOrder(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	defer func(old *Map) { CurrentMap = old }(CurrentMap)
+	CurrentMap = m
+
+	// The unit arrived, but the order isn't finished yet
+	p := &PoliceComponent{Unit: UnitType{Name: "Car"}}
+	pos := m.SnapToRoad(engo.Point{130, 150})
+	p.Current = NewOrder(CommandMove, pos.Point, m.AcquireNode(pos))
+	p.Current.Command = CommandHold
+
+	p.PreemptOrder(NewOrder(CommandLookout, engo.Point{}, nil))
+	if len(m.Nodes) != 9 || len(p.suspended) != 0 {
+		t.Fatal("the arrived order wasn't finished")
+	}
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransportNotCancellable(t *testing.T) {
+	p := &PoliceComponent{Unit: UnitType{Name: "Car"}, Arrested: 2}
+	transport := p.QueueOrder(NewOrder(CommandTransport, engo.Point{}, nil))
+	p.QueueOrder(NewOrder(CommandMove, engo.Point{}, nil))
+
+	if p.CancelOrder(transport) {
+		t.Fatal("cancelled bringing suspects to jail")
+	}
+	p.ClearOrders()
+	if len(p.Orders) != 1 || p.Orders[0].ID != transport {
+		t.Fatal(p.Orders)
+	}
+	p.Current = p.nextOrder()
+	if p.CancelOrder(transport) || p.Current.ID != transport {
+		t.Fatal(p.Current)
+	}
+
+	p.Arrested = 0
+	if !p.CancelOrder(transport) {
+		t.Fatal("can't cancel once the suspects are dropped off")
+	}
+}
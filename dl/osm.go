@@ -0,0 +1,421 @@
+package dl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"engo.io/engo"
+	"github.com/luxengine/math"
+)
+
+// earthRadius is used to project latitude and longitude onto the map, in meters
+const earthRadius = 6371000
+
+// OSMOptions configures how OpenStreetMap data is imported
+type OSMOptions struct {
+	// Tolerance is how far (in meters) simplified roads may deviate from the original ones; nodes which only
+	// describe the shape of a road are removed as long as the road stays within this distance
+	Tolerance float32
+	// KeepIslands keeps parts of the road network which aren't connected to the largest part of it
+	KeepIslands bool
+}
+
+// DefaultOSMOptions are used by the OSMLoader
+var DefaultOSMOptions = OSMOptions{
+	Tolerance: 5,
+}
+
+// osmHighways are the kinds of highway=* ways which are roads for cars, and what kind of road they are
+var osmHighways = map[string]RoadClass{
+	"motorway":       RoadHighway,
+	"motorway_link":  RoadHighway,
+	"trunk":          RoadHighway,
+	"trunk_link":     RoadHighway,
+	"primary":        RoadArterial,
+	"primary_link":   RoadArterial,
+	"secondary":      RoadArterial,
+	"secondary_link": RoadArterial,
+	"tertiary":       RoadArterial,
+	"tertiary_link":  RoadArterial,
+	"unclassified":   RoadResidential,
+	"residential":    RoadResidential,
+	"living_street":  RoadResidential,
+	"road":           RoadResidential,
+	"service":        RoadAlley,
+}
+
+type osmNode struct {
+	Lat, Lon float64
+}
+
+type osmWay struct {
+	Refs []int64
+	Tags map[string]string
+}
+
+// osmData is what's read from an OpenStreetMap file, regardless of its format
+type osmData struct {
+	Nodes map[int64]osmNode
+	Ways  []osmWay
+}
+
+func newOSMData() *osmData {
+	return &osmData{Nodes: make(map[int64]osmNode)}
+}
+
+// addWay keeps the way only if it's a road
+func (d *osmData) addWay(w osmWay) {
+	if _, ok := osmHighways[w.Tags["highway"]]; ok && len(w.Refs) > 1 {
+		d.Ways = append(d.Ways, w)
+	}
+}
+
+// ReadOSM imports the roads in an OpenStreetMap XML file (.osm)
+func ReadOSM(r io.Reader, opts OSMOptions) (*Map, error) {
+	data := newOSMData()
+	decoder := xml.NewDecoder(r)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "node":
+			var node struct {
+				ID  int64   `xml:"id,attr"`
+				Lat float64 `xml:"lat,attr"`
+				Lon float64 `xml:"lon,attr"`
+			}
+			if err := decoder.DecodeElement(&node, &start); err != nil {
+				return nil, err
+			}
+			data.Nodes[node.ID] = osmNode{node.Lat, node.Lon}
+		case "way":
+			var way struct {
+				Nds []struct {
+					Ref int64 `xml:"ref,attr"`
+				} `xml:"nd"`
+				Tags []struct {
+					Key   string `xml:"k,attr"`
+					Value string `xml:"v,attr"`
+				} `xml:"tag"`
+			}
+			if err := decoder.DecodeElement(&way, &start); err != nil {
+				return nil, err
+			}
+
+			w := osmWay{Tags: make(map[string]string)}
+			for _, nd := range way.Nds {
+				w.Refs = append(w.Refs, nd.Ref)
+			}
+			for _, tag := range way.Tags {
+				w.Tags[tag.Key] = tag.Value
+			}
+			data.addWay(w)
+		}
+	}
+
+	return data.build(opts)
+}
+
+// osmRoad returns the road described by the tags of a way, and in which directions it can be driven
+func osmRoad(tags map[string]string) (road Road, forward, backward bool) {
+	highway := tags["highway"]
+	road.Class = osmHighways[highway]
+	road.Name = tags["name"]
+	road.SpeedLimit = parseMaxSpeed(tags["maxspeed"])
+
+	forward, backward = true, true
+	oneway := tags["oneway"]
+	switch {
+	case oneway == "-1" || oneway == "reverse":
+		forward = false
+	case oneway == "yes" || oneway == "true" || oneway == "1":
+		backward = false
+	case oneway == "no":
+	case highway == "motorway" || tags["junction"] == "roundabout":
+		// These are one-way unless tagged otherwise
+		backward = false
+	}
+	road.OneWay = !forward || !backward
+	return
+}
+
+// parseMaxSpeed parses the maxspeed tag, which is in km/h unless specified otherwise. Unknown values return 0.
+func parseMaxSpeed(s string) float32 {
+	s = strings.TrimSpace(s)
+	factor := 1.0
+	if strings.HasSuffix(s, "mph") {
+		factor = 1.609344
+		s = strings.TrimSpace(strings.TrimSuffix(s, "mph"))
+	}
+	speed, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return 0
+	}
+	return float32(speed * factor)
+}
+
+// build projects the nodes onto the map and creates the roads
+func (d *osmData) build(opts OSMOptions) (*Map, error) {
+	if len(d.Ways) == 0 {
+		return nil, fmt.Errorf("no roads found")
+	}
+
+	// Nodes used by multiple ways, or at the end of one, are junctions which have to be kept
+	uses := make(map[int64]int)
+	for _, w := range d.Ways {
+		for i, ref := range w.Refs {
+			uses[ref]++
+			if i == 0 || i == len(w.Refs)-1 {
+				uses[ref]++
+			}
+		}
+	}
+
+	project, err := d.projection(uses)
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(Map)
+	ids := make(map[int64]*RouteNode)
+	node := func(ref int64, p engo.Point) *RouteNode {
+		if n, ok := ids[ref]; ok {
+			return n
+		}
+		n := &RouteNode{ID: uint32(len(m.Nodes) + 1), Location: p}
+		m.Nodes = append(m.Nodes, n)
+		ids[ref] = n
+		return n
+	}
+
+	// addPart adds the roads of a part of a way of which all nodes are in the extract
+	addPart := func(refs []int64, road Road, forward, backward bool) {
+		points := make([]engo.Point, len(refs))
+		for i, ref := range refs {
+			points[i] = project(d.Nodes[ref])
+		}
+
+		// Simplify every part of the way between two junctions
+		start := 0
+		for i := 1; i < len(refs); i++ {
+			if uses[refs[i]] < 2 && i < len(refs)-1 {
+				continue
+			}
+
+			part := points[start : i+1]
+			keep := simplifyLine(part, opts.Tolerance)
+			prev := 0
+			for _, k := range keep[1:] {
+				from := node(refs[start+prev], part[prev])
+				to := node(refs[start+k], part[k])
+				length := lineLength(part[prev : k+1])
+				prev = k
+				if from == to {
+					continue
+				}
+
+				if forward && from.Road(to.ID) == nil {
+					r := road
+					r.To, r.Length = to.ID, length
+					m.addRoad(from, to, &r)
+				}
+				if backward && to.Road(from.ID) == nil {
+					r := road
+					r.To, r.Length = from.ID, length
+					m.addRoad(to, from, &r)
+				}
+			}
+			start = i
+		}
+	}
+
+	for _, w := range d.Ways {
+		road, forward, backward := osmRoad(w.Tags)
+
+		// Ways leaving the extract are split where they do, as they may come back in somewhere else entirely
+		start := 0
+		for i := 0; i <= len(w.Refs); i++ {
+			if i < len(w.Refs) {
+				if _, ok := d.Nodes[w.Refs[i]]; ok {
+					continue
+				}
+			}
+			if i-start > 1 {
+				addPart(w.Refs[start:i], road, forward, backward)
+			}
+			start = i + 1
+		}
+	}
+
+	if !opts.KeepIslands {
+		m = m.largestPart()
+	}
+	return m, nil
+}
+
+// projection returns a function which projects latitude and longitude onto the map, in meters from the center of
+// all used nodes, with the y-axis pointing south
+func (d *osmData) projection(used map[int64]int) (func(osmNode) engo.Point, error) {
+	minLat, maxLat, minLon, maxLon := 90.0, -90.0, 180.0, -180.0
+	var found bool
+	for ref := range used {
+		n, ok := d.Nodes[ref]
+		if !ok {
+			continue
+		}
+		found = true
+		if n.Lat < minLat {
+			minLat = n.Lat
+		}
+		if n.Lat > maxLat {
+			maxLat = n.Lat
+		}
+		if n.Lon < minLon {
+			minLon = n.Lon
+		}
+		if n.Lon > maxLon {
+			maxLon = n.Lon
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("none of the roads' nodes are in the file")
+	}
+
+	// An equirectangular projection is accurate enough for the size of a town
+	lat0, lon0 := (minLat+maxLat)/2, (minLon+maxLon)/2
+	radians := float32(math.Pi / 180)
+	cosLat0 := math.Cos(float32(lat0) * radians)
+	return func(n osmNode) engo.Point {
+		return engo.Point{
+			X: earthRadius * float32(n.Lon-lon0) * radians * cosLat0,
+			Y: -earthRadius * float32(n.Lat-lat0) * radians,
+		}
+	}, nil
+}
+
+// simplifyLine uses the Douglas-Peucker algorithm to return the indices of the points which have to be kept for the
+// line to stay within the tolerance of the original. The first and last points are always kept.
+func simplifyLine(points []engo.Point, tolerance float32) []int {
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	var simplify func(from, to int)
+	simplify = func(from, to int) {
+		if to-from < 2 {
+			return
+		}
+		seg := RoadSegment{From: &RouteNode{Location: points[from]}, To: &RouteNode{Location: points[to]}}
+		furthest, distance := -1, tolerance
+		for i := from + 1; i < to; i++ {
+			if d := seg.Distance(points[i]); d > distance {
+				furthest, distance = i, d
+			}
+		}
+		if furthest < 0 {
+			return
+		}
+		keep[furthest] = true
+		simplify(from, furthest)
+		simplify(furthest, to)
+	}
+	simplify(0, len(points)-1)
+
+	var indices []int
+	for i, k := range keep {
+		if k {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func lineLength(points []engo.Point) float32 {
+	var length float32
+	for i := 1; i < len(points); i++ {
+		length += points[i-1].PointDistance(points[i])
+	}
+	return length
+}
+
+// largestPart returns a map containing only the largest part of the road network which is connected
+func (m *Map) largestPart() *Map {
+	m.prepare()
+	components := m.weakComponents()
+	largest := 0
+	for i, c := range components {
+		if len(c) > len(components[largest]) {
+			largest = i
+		}
+	}
+	if len(components) < 2 {
+		return m
+	}
+
+	part := &Map{Name: m.Name, Nodes: components[largest]}
+	part.prepare()
+	return part
+}
+
+// OSMLoader loads OpenStreetMap files, both XML (.osm) and PBF (.osm.pbf), as a Map
+type OSMLoader struct {
+	Options *OSMOptions
+
+	maps map[string]*Map
+}
+
+func (ol *OSMLoader) Load(url string, data io.Reader) error {
+	if ol.maps == nil {
+		ol.maps = make(map[string]*Map)
+	}
+
+	opts := DefaultOSMOptions
+	if ol.Options != nil {
+		opts = *ol.Options
+	}
+
+	var (
+		m   *Map
+		err error
+	)
+	if strings.HasSuffix(url, ".pbf") {
+		m, err = ReadOSMPBF(data, opts)
+	} else {
+		m, err = ReadOSM(data, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to import %s: %v", url, err)
+	}
+
+	m.Name = url
+	ol.maps[url] = m
+	return nil
+}
+
+func (ol *OSMLoader) Unload(url string) error {
+	delete(ol.maps, url)
+	return nil
+}
+
+func (ol *OSMLoader) Resource(url string) (engo.Resource, error) {
+	m, exists := ol.maps[url]
+	if !exists {
+		return nil, fmt.Errorf("map resource was not found in memory: %s", url)
+	}
+
+	return m, nil
+}
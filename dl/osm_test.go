@@ -0,0 +1,105 @@
+package dl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readSample(t *testing.T, filename string) *Map {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	read := ReadOSM
+	if filepath.Ext(filename) == ".pbf" {
+		read = ReadOSMPBF
+	}
+	m, err := read(f, DefaultOSMOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.prepare()
+	return m
+}
+
+func TestReadOSM(t *testing.T) {
+	m := readSample(t, "sample.osm")
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+	// The nodes are numbered in the order the ways use them, as noted in the file
+	if len(m.Nodes) != 8 {
+		t.Fatalf("%d nodes, expected 8", len(m.Nodes))
+	}
+
+	road := func(from, to uint32) *Road {
+		return m.Node(from).Road(to)
+	}
+	tests := []struct {
+		name       string
+		from, to   uint32
+		class      RoadClass
+		speedLimit float32
+		oneWay     bool
+		length     float32
+	}{
+		{"Main Street", 1, 2, RoadResidential, 48.28, false, 111.2},
+		{"Main Street", 2, 1, RoadResidential, 48.28, false, 111.2},
+		{"High Street", 3, 2, RoadArterial, 50, true, 67.7},
+		{"High Street", 2, 4, RoadArterial, 50, true, 67.7},
+		{"Curved Lane", 4, 5, RoadResidential, 0, false, 65.1},
+		{"Curved Lane", 6, 5, RoadResidential, 0, false, 65.1},
+		{"Loop Road", 6, 7, RoadAlley, 0, false, 57.2},
+		{"Loop Road", 1, 8, RoadAlley, 0, false, 57.2},
+	}
+	for _, tt := range tests {
+		r := road(tt.from, tt.to)
+		if r == nil {
+			t.Fatalf("no road from %d to %d", tt.from, tt.to)
+		}
+		if r.Name != tt.name || r.Class != tt.class || r.OneWay != tt.oneWay {
+			t.Errorf("road from %d to %d is %+v", tt.from, tt.to, r)
+		}
+		if d := r.SpeedLimit - tt.speedLimit; d > 0.01 || d < -0.01 {
+			t.Errorf("road from %d to %d has speed limit %f, expected %f", tt.from, tt.to, r.SpeedLimit, tt.speedLimit)
+		}
+		if d := r.Length - tt.length; d > 0.5 || d < -0.5 {
+			t.Errorf("road from %d to %d is %f long, expected %f", tt.from, tt.to, r.Length, tt.length)
+		}
+	}
+
+	// One-way roads can't be driven the other way, and the loop doesn't get a road where it left the extract
+	for _, ends := range [][2]uint32{{2, 3}, {4, 2}, {7, 8}, {8, 7}} {
+		if road(ends[0], ends[1]) != nil {
+			t.Errorf("unexpected road from %d to %d", ends[0], ends[1])
+		}
+	}
+}
+
+func TestReadOSMPBF(t *testing.T) {
+	want, got := readSample(t, "sample.osm"), readSample(t, "sample.osm.pbf")
+	if err := got.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Nodes) != len(want.Nodes) {
+		t.Fatalf("%d nodes, expected %d", len(got.Nodes), len(want.Nodes))
+	}
+
+	for _, w := range want.Nodes {
+		n := got.Node(w.ID)
+		if n == nil || n.Location.PointDistance(w.Location) > 0.01 || len(n.Roads) != len(w.Roads) {
+			t.Fatalf("node %v, expected %v", n, w)
+		}
+		for _, wr := range w.Roads {
+			r := n.Road(wr.To)
+			if r == nil || r.Name != wr.Name || r.Class != wr.Class || r.SpeedLimit != wr.SpeedLimit ||
+				r.OneWay != wr.OneWay || r.Length-wr.Length > 0.01 || wr.Length-r.Length > 0.01 {
+				t.Fatalf("road from %d to %d is %+v, expected %+v", w.ID, wr.To, r, wr)
+			}
+		}
+	}
+}
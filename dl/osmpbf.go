@@ -0,0 +1,355 @@
+package dl
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// maxBlobSize is the maximum size of a (compressed) block in a PBF file, as defined by the format
+const maxBlobSize = 32 * 1024 * 1024
+
+var errProtobuf = errors.New("invalid protobuf data")
+
+// ReadOSMPBF imports the roads in an OpenStreetMap PBF file (.osm.pbf)
+func ReadOSMPBF(r io.Reader, opts OSMOptions) (*Map, error) {
+	data := newOSMData()
+
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		header := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		blobType, blobSize, err := readBlobHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		if blobSize > maxBlobSize {
+			return nil, fmt.Errorf("block of %d bytes is too large", blobSize)
+		}
+
+		blob := make([]byte, blobSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return nil, err
+		}
+		if blobType != "OSMData" {
+			continue
+		}
+
+		block, err := readBlob(blob)
+		if err != nil {
+			return nil, err
+		}
+		if err := data.readPrimitiveBlock(block); err != nil {
+			return nil, err
+		}
+	}
+
+	return data.build(opts)
+}
+
+func readBlobHeader(b []byte) (blobType string, size int, err error) {
+	p := protoReader{buf: b}
+	for field, wire, ok := p.next(); ok; field, wire, ok = p.next() {
+		switch field {
+		case 1:
+			blobType = string(p.bytes())
+		case 3:
+			size = int(p.varint())
+		default:
+			p.skip(wire)
+		}
+	}
+	return blobType, size, p.err
+}
+
+// readBlob returns the uncompressed contents of a blob
+func readBlob(b []byte) ([]byte, error) {
+	p := protoReader{buf: b}
+	for field, wire, ok := p.next(); ok; field, wire, ok = p.next() {
+		switch field {
+		case 1:
+			return p.bytes(), p.err
+		case 3:
+			z, err := zlib.NewReader(bytes.NewReader(p.bytes()))
+			if err != nil {
+				return nil, err
+			}
+			defer z.Close()
+			return ioutil.ReadAll(io.LimitReader(z, maxBlobSize))
+		default:
+			p.skip(wire)
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return nil, fmt.Errorf("unsupported block compression")
+}
+
+func (d *osmData) readPrimitiveBlock(b []byte) error {
+	var (
+		stringTable []string
+		groups      [][]byte
+		granularity int64 = 100
+		latOffset   int64
+		lonOffset   int64
+	)
+
+	p := protoReader{buf: b}
+	for field, wire, ok := p.next(); ok; field, wire, ok = p.next() {
+		switch field {
+		case 1:
+			table := protoReader{buf: p.bytes()}
+			for field, wire, ok := table.next(); ok; field, wire, ok = table.next() {
+				if field == 1 {
+					stringTable = append(stringTable, string(table.bytes()))
+				} else {
+					table.skip(wire)
+				}
+			}
+			if table.err != nil {
+				return table.err
+			}
+		case 2:
+			groups = append(groups, p.bytes())
+		case 17:
+			granularity = int64(p.varint())
+		case 19:
+			latOffset = int64(p.varint())
+		case 20:
+			lonOffset = int64(p.varint())
+		default:
+			p.skip(wire)
+		}
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	str := func(i uint64) string {
+		if i < uint64(len(stringTable)) {
+			return stringTable[i]
+		}
+		return ""
+	}
+	coord := func(lat, lon int64) osmNode {
+		return osmNode{
+			Lat: 1e-9 * float64(latOffset+granularity*lat),
+			Lon: 1e-9 * float64(lonOffset+granularity*lon),
+		}
+	}
+
+	for _, group := range groups {
+		g := protoReader{buf: group}
+		for field, wire, ok := g.next(); ok; field, wire, ok = g.next() {
+			var err error
+			switch field {
+			case 1:
+				err = d.readNode(g.bytes(), coord)
+			case 2:
+				err = d.readDenseNodes(g.bytes(), coord)
+			case 3:
+				err = d.readWay(g.bytes(), str)
+			default:
+				g.skip(wire)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if g.err != nil {
+			return g.err
+		}
+	}
+	return nil
+}
+
+func (d *osmData) readNode(b []byte, coord func(lat, lon int64) osmNode) error {
+	var id, lat, lon int64
+	p := protoReader{buf: b}
+	for field, wire, ok := p.next(); ok; field, wire, ok = p.next() {
+		switch field {
+		case 1:
+			id = zigzag(p.varint())
+		case 8:
+			lat = zigzag(p.varint())
+		case 9:
+			lon = zigzag(p.varint())
+		default:
+			p.skip(wire)
+		}
+	}
+	d.Nodes[id] = coord(lat, lon)
+	return p.err
+}
+
+func (d *osmData) readDenseNodes(b []byte, coord func(lat, lon int64) osmNode) error {
+	var ids, lats, lons []uint64
+	p := protoReader{buf: b}
+	for field, wire, ok := p.next(); ok; field, wire, ok = p.next() {
+		switch field {
+		case 1:
+			ids = p.packed(wire, ids)
+		case 8:
+			lats = p.packed(wire, lats)
+		case 9:
+			lons = p.packed(wire, lons)
+		default:
+			p.skip(wire)
+		}
+	}
+	if p.err != nil {
+		return p.err
+	}
+	if len(ids) != len(lats) || len(ids) != len(lons) {
+		return errProtobuf
+	}
+
+	// Everything is delta coded
+	var id, lat, lon int64
+	for i := range ids {
+		id += zigzag(ids[i])
+		lat += zigzag(lats[i])
+		lon += zigzag(lons[i])
+		d.Nodes[id] = coord(lat, lon)
+	}
+	return nil
+}
+
+func (d *osmData) readWay(b []byte, str func(uint64) string) error {
+	var keys, vals, refs []uint64
+	p := protoReader{buf: b}
+	for field, wire, ok := p.next(); ok; field, wire, ok = p.next() {
+		switch field {
+		case 2:
+			keys = p.packed(wire, keys)
+		case 3:
+			vals = p.packed(wire, vals)
+		case 8:
+			refs = p.packed(wire, refs)
+		default:
+			p.skip(wire)
+		}
+	}
+	if p.err != nil {
+		return p.err
+	}
+	if len(keys) != len(vals) {
+		return errProtobuf
+	}
+
+	w := osmWay{Tags: make(map[string]string, len(keys)), Refs: make([]int64, len(refs))}
+	for i := range keys {
+		w.Tags[str(keys[i])] = str(vals[i])
+	}
+	var ref int64
+	for i := range refs {
+		ref += zigzag(refs[i])
+		w.Refs[i] = ref
+	}
+	d.addWay(w)
+	return nil
+}
+
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// protoReader reads the fields of a protobuf message, just enough for the PBF format. The first error is kept,
+// after which no more fields are read.
+type protoReader struct {
+	buf []byte
+	err error
+}
+
+const (
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+	wire32Bit  = 5
+)
+
+func (p *protoReader) next() (field, wire int, ok bool) {
+	if p.err != nil || len(p.buf) == 0 {
+		return 0, 0, false
+	}
+	key := p.varint()
+	return int(key >> 3), int(key & 7), p.err == nil
+}
+
+func (p *protoReader) varint() uint64 {
+	v, n := binary.Uvarint(p.buf)
+	if n <= 0 {
+		p.fail()
+		return 0
+	}
+	p.buf = p.buf[n:]
+	return v
+}
+
+func (p *protoReader) bytes() []byte {
+	n := p.varint()
+	if p.err != nil || n > uint64(len(p.buf)) {
+		p.fail()
+		return nil
+	}
+	b := p.buf[:n]
+	p.buf = p.buf[n:]
+	return b
+}
+
+// packed reads a repeated varint field, which may or may not be packed
+func (p *protoReader) packed(wire int, values []uint64) []uint64 {
+	if wire != wireBytes {
+		return append(values, p.varint())
+	}
+	inner := protoReader{buf: p.bytes()}
+	for len(inner.buf) > 0 && inner.err == nil {
+		values = append(values, inner.varint())
+	}
+	if inner.err != nil {
+		p.fail()
+	}
+	return values
+}
+
+func (p *protoReader) skip(wire int) {
+	var n int
+	switch wire {
+	case wireVarint:
+		p.varint()
+		return
+	case wireBytes:
+		p.bytes()
+		return
+	case wire64Bit:
+		n = 8
+	case wire32Bit:
+		n = 4
+	default:
+		p.fail()
+		return
+	}
+	if n > len(p.buf) {
+		p.fail()
+		return
+	}
+	p.buf = p.buf[n:]
+}
+
+func (p *protoReader) fail() {
+	p.err = errProtobuf
+	p.buf = nil
+}
@@ -1,59 +1,136 @@
 package dl
 
 import (
+	"errors"
+
 	"engo.io/engo"
 )
 
-func SetRoute(from, to engo.Point, h func(curr, goal, pos *RouteNode) float32) Route {
-	// Go to node closest to where we wanna go
-	dest := CurrentMap.NearestNode(to)
+var (
+	ErrNoRoute = errors.New("no route found")
+	ErrNoMap   = errors.New("no map loaded")
+)
 
-	// Going for an A* algorithm, with Euclidean-distance as heuristic (including the cost)
+// EdgeCost returns the cost of driving over the road, which starts at the given node. Costs should never be negative.
+type EdgeCost func(from *RouteNode, road *Road) float32
 
-	visited := make(map[uint32]struct{})
-	curr := CurrentMap.NearestNode(from)
+// Heuristic estimates the cost of getting from pos to goal. The route found is only guaranteed to be optimal if the
+// heuristic never overestimates the actual cost.
+type Heuristic func(pos, goal *RouteNode) float32
 
-	type queueItem struct {
-		Route Route
+// TravelTimeCost uses the time (in seconds) it takes a unit driving at most at maxSpeed as cost
+func TravelTimeCost(maxSpeed float32) EdgeCost {
+	return func(from *RouteNode, road *Road) float32 {
+		return road.TravelTime(maxSpeed)
 	}
+}
 
-	var queue PriorityQueue
-	queue.Enqueue(queueItem{Route: Route{Nodes: []*RouteNode{curr}}}, 0)
+// TravelTimeHeuristic estimates the time it takes to drive in a straight line to the goal at maxSpeed. Since no road
+// allows driving faster than maxSpeed, it is admissible for TravelTimeCost.
+func TravelTimeHeuristic(maxSpeed float32) Heuristic {
+	return func(pos, goal *RouteNode) float32 {
+		return pos.Location.PointDistance(goal.Location) / (maxSpeed / 3.6)
+	}
+}
 
-	var goalReached bool
-	var route Route
+// DistanceCost uses the length of the road as cost
+func DistanceCost(from *RouteNode, road *Road) float32 {
+	return road.Length
+}
+
+// DistanceHeuristic is the Euclidean distance to the goal, which is admissible for DistanceCost
+func DistanceHeuristic(pos, goal *RouteNode) float32 {
+	return pos.Location.PointDistance(goal.Location)
+}
+
+// SetRoute computes a route on the CurrentMap between the nodes nearest to the two points
+func SetRoute(from, to engo.Point, cost EdgeCost, h Heuristic) (Route, error) {
+	if CurrentMap == nil || len(CurrentMap.Nodes) == 0 {
+		return Route{}, ErrNoMap
+	}
+
+	return CurrentMap.FindRoute(CurrentMap.NearestNode(from), CurrentMap.NearestNode(to), cost, h)
+}
 
-	for !goalReached && len(queue.values) > 0 {
-		// Dequeue
-		next := queue.Dequeue()
-		n := next.(queueItem)
-		nNode := n.Route.Nodes[len(n.Route.Nodes)-1]
+// FindRoute uses A* to find the route with the lowest cost between the two nodes. Closed roads are only used to leave
+// the start or to reach the destination.
+func (m *Map) FindRoute(start, dest *RouteNode, cost EdgeCost, h Heuristic) (Route, error) {
+	type step struct {
+		prev *RouteNode
+		road *Road
+	}
+
+	var (
+		queue    PriorityQueue[*RouteNode]
+		gScore   = map[uint32]float32{start.ID: 0}
+		cameFrom = make(map[uint32]step)
+		closed   = make(map[uint32]struct{})
+	)
+	queue.Enqueue(start, h(start, dest))
 
-		if nNode.ID == dest.ID {
-			goalReached = true
-			route = n.Route
-			break
+	for queue.Len() > 0 {
+		curr := queue.Dequeue()
+		closed[curr.ID] = struct{}{}
+
+		if curr.ID == dest.ID {
+			route := reconstructRoute(dest, func(n *RouteNode) (*RouteNode, *Road) {
+				s, ok := cameFrom[n.ID]
+				if !ok {
+					return nil, nil
+				}
+				return s.prev, s.road
+			})
+			route.planned = m.version
+			return route, nil
 		}
 
-		for _, connID := range nNode.ConnectedTo {
-			if _, ok := visited[connID]; ok {
-				continue // don't queue whatever we've already queued once
+		for _, road := range curr.Roads {
+			if _, ok := closed[road.To]; ok || (road.Closed() && curr != start && road.To != dest.ID) {
+				continue
 			}
 
-			childNode := CurrentMap.Node(connID)
-			heuristic := h(curr, dest, childNode)
+			child := m.Node(road.To)
+			if child == nil {
+				continue
+			}
 
-			oldRoute := make([]*RouteNode, len(n.Route.Nodes), len(n.Route.Nodes)+1)
-			copy(oldRoute, n.Route.Nodes)
-			queue.Enqueue(queueItem{Route: Route{Nodes: append(oldRoute, childNode)}}, heuristic)
+			g := gScore[curr.ID] + cost(curr, road)
+			if known, ok := gScore[child.ID]; ok && known <= g {
+				continue
+			}
 
-			visited[connID] = struct{}{}
+			gScore[child.ID] = g
+			cameFrom[child.ID] = step{curr, road}
+			queue.Enqueue(child, g+h(child, dest)) // decreases the priority if it's already queued
 		}
 	}
 
-	if !goalReached {
-		panic("No route found")
+	return Route{}, ErrNoRoute
+}
+
+// reconstructRoute walks back from the destination using the given function, until it returns no previous node
+func reconstructRoute(dest *RouteNode, prev func(*RouteNode) (*RouteNode, *Road)) Route {
+	var route Route
+	for n := dest; n != nil; {
+		p, road := prev(n)
+		route.Nodes = append(route.Nodes, n)
+		route.Roads = append(route.Roads, road)
+		n = p
+	}
+
+	for i, j := 0, len(route.Nodes)-1; i < j; i, j = i+1, j-1 {
+		route.Nodes[i], route.Nodes[j] = route.Nodes[j], route.Nodes[i]
+		route.Roads[i], route.Roads[j] = route.Roads[j], route.Roads[i]
 	}
 
 	return route
 }
+
+// Cost returns the total cost of the route
+func (r Route) Cost(cost EdgeCost) float32 {
+	var total float32
+	for i := 1; i < len(r.Nodes); i++ {
+		total += cost(r.Nodes[i-1], r.Roads[i])
+	}
+	return total
+}
@@ -0,0 +1,174 @@
+package dl
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"engo.io/engo"
+)
+
+// cheapestCost uses Dijkstra's algorithm to find the cost of the cheapest route, or -1 if there is none
+func cheapestCost(m *Map, start, dest *RouteNode, cost EdgeCost) float32 {
+	dist := map[uint32]float32{start.ID: 0}
+	done := make(map[uint32]bool)
+	for {
+		var (
+			best  uint32
+			found bool
+		)
+		for id, d := range dist {
+			if !done[id] && (!found || d < dist[best]) {
+				best, found = id, true
+			}
+		}
+		if !found {
+			return -1
+		}
+		if best == dest.ID {
+			return dist[best]
+		}
+		done[best] = true
+		n := m.Node(best)
+		for _, road := range n.Roads {
+			if d, ok := dist[road.To]; !ok || dist[best]+cost(n, road) < d {
+				dist[road.To] = dist[best] + cost(n, road)
+			}
+		}
+	}
+}
+
+func checkRoute(t *testing.T, route Route, start, dest *RouteNode) {
+	t.Helper()
+	if route.Nodes[0] != start || route.Nodes[len(route.Nodes)-1] != dest || route.Roads[0] != nil {
+		t.Fatalf("route from %v to %v: %v", start, dest, route)
+	}
+	for i := 1; i < len(route.Nodes); i++ {
+		if route.Nodes[i-1].Road(route.Nodes[i].ID) != route.Roads[i] {
+			t.Fatalf("road %d of the route doesn't lead to node %v", i, route.Nodes[i])
+		}
+	}
+}
+
+func TestFindRouteOptimal(t *testing.T) {
+	tests := []struct {
+		name       string
+		w, h       uint32
+		speedLimit bool
+		oneWay     int
+		cost       EdgeCost
+		heuristic  Heuristic
+	}{
+		{"distance", 12, 9, false, 0, DistanceCost, DistanceHeuristic},
+		{"travel time", 12, 9, false, 0, TravelTimeCost(220), TravelTimeHeuristic(220)},
+		{"speed limits", 12, 9, true, 0, TravelTimeCost(220), TravelTimeHeuristic(220)},
+		{"one-way", 10, 10, true, 15, TravelTimeCost(220), TravelTimeHeuristic(220)},
+		{"single street", 1, 20, false, 0, DistanceCost, DistanceHeuristic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := RandomMap(tt.w, tt.h, 100, 80)
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < tt.oneWay; i++ {
+				n := m.Nodes[rng.Intn(len(m.Nodes))]
+				n.Roads[rng.Intn(len(n.Roads))].OneWay = true
+			}
+			m.Initialize()
+			if tt.speedLimit {
+				for _, n := range m.Nodes {
+					for _, road := range n.Roads {
+						road.SpeedLimit = float32(20 + rng.Intn(200))
+					}
+				}
+			}
+
+			for k := 0; k < 100; k++ {
+				start, dest := m.Nodes[rng.Intn(len(m.Nodes))], m.Nodes[rng.Intn(len(m.Nodes))]
+				want := cheapestCost(m, start, dest, tt.cost)
+				route, err := m.FindRoute(start, dest, tt.cost, tt.heuristic)
+				if want < 0 {
+					if !errors.Is(err, ErrNoRoute) {
+						t.Fatalf("no route from %v to %v, but got %v", start, dest, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkRoute(t, route, start, dest)
+				if d := route.Cost(tt.cost) - want; d > 1e-3 || d < -1e-3 {
+					t.Fatalf("route from %v to %v costs %f, but the cheapest costs %f", start, dest, route.Cost(tt.cost), want)
+				}
+			}
+		})
+	}
+}
+
+// lineMap returns a map of nodes on a line, 10 apart, which are connected to the next one according to the roads
+func lineMap(roads ...bool) *Map {
+	m := &Map{Nodes: []*RouteNode{{ID: 1}}}
+	for i, road := range roads {
+		n := &RouteNode{ID: uint32(i + 2), Location: engo.Point{X: float32(i+1) * 10}}
+		if road {
+			prev := m.Nodes[i]
+			prev.ConnectedTo = append(prev.ConnectedTo, n.ID)
+			n.ConnectedTo = append(n.ConnectedTo, prev.ID)
+		}
+		m.Nodes = append(m.Nodes, n)
+	}
+	m.Initialize()
+	return m
+}
+
+func TestFindRouteEnds(t *testing.T) {
+	tests := []struct {
+		name        string
+		roads       []bool
+		closed      []int
+		start, dest uint32
+		nodes       int
+		err         error
+	}{
+		{"start is the destination", []bool{true, true}, nil, 2, 2, 1, nil},
+		{"start is the destination on its own", []bool{false, false}, nil, 2, 2, 1, nil},
+		{"connected", []bool{true, true, true}, nil, 1, 4, 4, nil},
+		{"disconnected", []bool{true, false, true}, nil, 1, 4, 0, ErrNoRoute},
+		{"closed start road", []bool{true, true, true}, []int{1}, 1, 4, 4, nil},
+		{"closed destination road", []bool{true, true, true}, []int{3}, 1, 4, 4, nil},
+		{"closed start and destination road", []bool{true}, []int{1}, 1, 2, 2, nil},
+		{"closed road in between", []bool{true, true, true}, []int{2}, 1, 4, 0, ErrNoRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := lineMap(tt.roads...)
+			// Road i is closed in both directions, between node i and node i+1
+			for _, i := range tt.closed {
+				a, b := m.Node(uint32(i)), m.Node(uint32(i+1))
+				m.CloseRoads(a.Road(b.ID), b.Road(a.ID))
+			}
+
+			start, dest := m.Node(tt.start), m.Node(tt.dest)
+			route, err := m.FindRoute(start, dest, DistanceCost, DistanceHeuristic)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, expected %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			checkRoute(t, route, start, dest)
+			if len(route.Nodes) != tt.nodes {
+				t.Fatalf("route has %d nodes, expected %d", len(route.Nodes), tt.nodes)
+			}
+		})
+	}
+}
+
+func TestSetRouteNoMap(t *testing.T) {
+	defer func(old *Map) { CurrentMap = old }(CurrentMap)
+	CurrentMap = nil
+	if _, err := SetRoute(engo.Point{}, engo.Point{1, 1}, DistanceCost, DistanceHeuristic); !errors.Is(err, ErrNoMap) {
+		t.Fatal(err)
+	}
+}
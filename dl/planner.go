@@ -0,0 +1,342 @@
+package dl
+
+import (
+	"github.com/luxengine/math"
+)
+
+// unreachable is the cost of getting to a node the planner hasn't found a way to yet
+const unreachable float32 = math.MaxFloat32
+
+// Planner keeps the cheapest route between two nodes up to date while both of them move and the map changes, like
+// when chasing someone. Instead of searching from scratch every time, it repeats only the part of the previous search
+// that is affected by the changes (this is Moving Target D* Lite). The heuristic has to be consistent: it should
+// never overestimate the cost of a road, nor the cost of getting from one node to another.
+type Planner struct {
+	Map       *Map
+	Cost      EdgeCost
+	Heuristic Heuristic
+
+	start, goal *RouteNode
+	// km is added to all priorities, so the queue doesn't have to be rebuilt when the goal moves
+	km       float32
+	states   map[*RouteNode]*plannerState
+	queue    PriorityQueue[*RouteNode]
+	incoming map[uint32][]RoadSegment
+	version  uint64
+	traffic  uint64
+	// last is the route found last time, which stays the same until something changes
+	last Route
+}
+
+// plannerState holds the cost of the cheapest known way from the start to a node (g), and what it would be given the
+// costs of its neighbours (rhs). Nodes where the two differ are queued.
+type plannerState struct {
+	g, rhs float32
+}
+
+// NewPlanner returns a planner for routes on the map, which are the cheapest according to the cost function
+func NewPlanner(m *Map, cost EdgeCost, h Heuristic) *Planner {
+	return &Planner{Map: m, Cost: cost, Heuristic: h}
+}
+
+// Route returns the cheapest route between the nodes. Just like with FindRoute, closed roads are only used to leave
+// the start or to reach the goal.
+func (p *Planner) Route(start, goal *RouteNode) (Route, error) {
+	if p.Map.Node(start.ID) != start || p.Map.Node(goal.ID) != goal {
+		return Route{}, ErrNoRoute
+	}
+
+	switch {
+	case p.states == nil || p.Map.Node(p.start.ID) != p.start:
+		p.reset(start, goal)
+	default:
+		if p.version != p.Map.Version() {
+			p.mapChanged()
+		}
+		if p.traffic != p.Map.TrafficVersion() {
+			p.trafficChanged()
+		}
+		if goal != p.goal {
+			p.moveGoal(goal)
+		}
+		if start != p.start {
+			p.moveStart(start)
+		}
+	}
+
+	if p.last.Nodes == nil {
+		p.computeShortestPath()
+		route, err := p.route()
+		if err != nil {
+			return route, err
+		}
+		p.last = route
+	}
+
+	// The caller gets a copy, as it's free to change the route
+	return Route{
+		Nodes:   append([]*RouteNode(nil), p.last.Nodes...),
+		Roads:   append([]*Road(nil), p.last.Roads...),
+		planned: p.last.planned,
+	}, nil
+}
+
+func (p *Planner) reset(start, goal *RouteNode) {
+	p.start, p.goal, p.km = start, goal, 0
+	p.last = Route{}
+	p.states = make(map[*RouteNode]*plannerState)
+	p.queue = PriorityQueue[*RouteNode]{}
+	p.buildIncoming()
+
+	p.state(start).rhs = 0
+	p.queue.Enqueue(start, p.key(start))
+}
+
+func (p *Planner) buildIncoming() {
+	p.version, p.traffic = p.Map.Version(), p.Map.TrafficVersion()
+	p.incoming = make(map[uint32][]RoadSegment)
+	for _, node := range p.Map.Nodes {
+		for _, road := range node.Roads {
+			if to := p.Map.Node(road.To); to != nil {
+				p.incoming[to.ID] = append(p.incoming[to.ID], RoadSegment{node, to, road})
+			}
+		}
+	}
+}
+
+// mapChanged updates the nodes which the changed roads lead to. If the map doesn't remember what changed, every node
+// the search has reached so far is checked, as well as the nodes they lead to.
+func (p *Planner) mapChanged() {
+	p.last = Route{}
+	changes, ok := p.Map.ChangedRoads(p.version)
+	if !ok {
+		p.buildIncoming()
+		p.checkAll()
+		return
+	}
+	p.version = p.Map.Version()
+
+	check := make(map[*RouteNode]struct{})
+	for _, change := range changes {
+		if change.From != nil {
+			// The road was added, removed or moved
+			p.removeIncoming(change.Road)
+			if p.Map.HasRoad(change.RoadSegment) {
+				p.incoming[change.To.ID] = append(p.incoming[change.To.ID], change.RoadSegment)
+			}
+		}
+		if to := p.Map.Node(change.Road.To); to != nil {
+			check[to] = struct{}{}
+		}
+	}
+
+	// Nodes which were removed are forgotten
+	for _, change := range changes {
+		for _, node := range []*RouteNode{change.From, change.To} {
+			if node != nil && p.Map.Node(node.ID) != node {
+				delete(p.states, node)
+				p.queue.Remove(node)
+			}
+		}
+	}
+	for node := range check {
+		p.updateNode(node)
+	}
+}
+
+// removeIncoming removes the road from the roads leading to its target
+func (p *Planner) removeIncoming(road *Road) {
+	incoming := p.incoming[road.To]
+	for i, seg := range incoming {
+		if seg.Road == road {
+			incoming = append(incoming[:i], incoming[i+1:]...)
+			break
+		}
+	}
+	if len(incoming) == 0 {
+		delete(p.incoming, road.To)
+	} else {
+		p.incoming[road.To] = incoming
+	}
+}
+
+// checkAll checks every node the search has reached so far, and the nodes they lead to, for changes in the cost of
+// getting there
+func (p *Planner) checkAll() {
+	check := make(map[*RouteNode]struct{}, len(p.states))
+	for node := range p.states {
+		if p.Map.Node(node.ID) != node {
+			delete(p.states, node)
+			p.queue.Remove(node)
+			continue
+		}
+		check[node] = struct{}{}
+		for _, road := range node.Roads {
+			if to := p.Map.Node(road.To); to != nil {
+				check[to] = struct{}{}
+			}
+		}
+	}
+	for node := range check {
+		p.updateNode(node)
+	}
+}
+
+// trafficChanged checks the nodes which roads the search has reached lead to, if the traffic on those roads changed.
+// Unlike changes to the map, the roads themselves stay the same.
+func (p *Planner) trafficChanged() {
+	check := make(map[*RouteNode]struct{})
+	for node := range p.states {
+		for _, road := range node.Roads {
+			if to := p.Map.Node(road.To); to != nil && road.trafficChanged > p.traffic {
+				check[to] = struct{}{}
+			}
+		}
+	}
+	p.traffic = p.Map.TrafficVersion()
+	if len(check) > 0 {
+		p.last = Route{}
+	}
+	for node := range check {
+		p.updateNode(node)
+	}
+}
+
+// moveGoal makes the search look for another node. Closed roads can be used to reach the goal, so the cost of getting
+// to both the old and the new goal changes.
+func (p *Planner) moveGoal(goal *RouteNode) {
+	old := p.goal
+	p.km += p.Heuristic(old, goal)
+	p.goal = goal
+	p.last = Route{}
+	if p.Map.Node(old.ID) == old {
+		p.updateNode(old)
+	}
+	p.updateNode(goal)
+}
+
+// moveStart makes the search start from another node. Everything found so far stays valid, except for what depended
+// on the old start.
+func (p *Planner) moveStart(start *RouteNode) {
+	old := p.start
+	p.start = start
+	p.last = Route{}
+	for _, node := range []*RouteNode{old, start} {
+		if p.Map.Node(node.ID) != node {
+			continue
+		}
+		p.updateNode(node)
+		// Closed roads can be used to leave the start, so the costs of its neighbours change as well
+		p.updateSuccessors(node)
+	}
+}
+
+func (p *Planner) state(n *RouteNode) *plannerState {
+	s, ok := p.states[n]
+	if !ok {
+		s = &plannerState{g: unreachable, rhs: unreachable}
+		p.states[n] = s
+	}
+	return s
+}
+
+func (p *Planner) key(n *RouteNode) float32 {
+	s := p.state(n)
+	return math.Min(s.g, s.rhs) + p.Heuristic(n, p.goal) + p.km
+}
+
+func (p *Planner) cost(from *RouteNode, road *Road) float32 {
+	if road.Closed() && from != p.start && road.To != p.goal.ID {
+		return unreachable
+	}
+	return p.Cost(from, road)
+}
+
+// best returns the road by which the node is reached the cheapest, and what that costs
+func (p *Planner) best(n *RouteNode) (RoadSegment, float32) {
+	var (
+		best RoadSegment
+		min  = unreachable
+	)
+	for _, seg := range p.incoming[n.ID] {
+		s, ok := p.states[seg.From]
+		if !ok || s.g == unreachable {
+			continue
+		}
+		if c := p.cost(seg.From, seg.Road); c != unreachable && s.g+c < min {
+			best, min = seg, s.g+c
+		}
+	}
+	return best, min
+}
+
+func (p *Planner) updateNode(n *RouteNode) {
+	s := p.state(n)
+	if n == p.start {
+		s.rhs = 0
+	} else {
+		_, s.rhs = p.best(n)
+	}
+
+	if s.g != s.rhs {
+		p.queue.Enqueue(n, p.key(n))
+	} else {
+		p.queue.Remove(n)
+	}
+}
+
+func (p *Planner) updateSuccessors(n *RouteNode) {
+	for _, road := range n.Roads {
+		if to := p.Map.Node(road.To); to != nil {
+			p.updateNode(to)
+		}
+	}
+}
+
+func (p *Planner) computeShortestPath() {
+	for {
+		// Nodes with the same priority as the goal may still change the cost of getting there, so they are handled as
+		// well. The margin keeps rounding errors from hiding them.
+		n, k, ok := p.queue.Peek()
+		goal, goalKey := p.state(p.goal), p.key(p.goal)
+		if !ok || (k > goalKey+goalKey*1e-5 && goal.g == goal.rhs) {
+			return
+		}
+
+		s := p.state(n)
+		switch {
+		case k < p.key(n):
+			p.queue.Update(n, p.key(n))
+		case s.g > s.rhs:
+			s.g = s.rhs
+			p.queue.Remove(n)
+			p.updateSuccessors(n)
+		default:
+			s.g = unreachable
+			p.updateNode(n)
+			p.updateSuccessors(n)
+		}
+	}
+}
+
+// route walks back from the goal to the start, over the cheapest roads
+func (p *Planner) route() (Route, error) {
+	if p.state(p.goal).g == unreachable {
+		return Route{}, ErrNoRoute
+	}
+
+	steps := 0
+	route := reconstructRoute(p.goal, func(n *RouteNode) (*RouteNode, *Road) {
+		if n == p.start || steps > len(p.states) {
+			return nil, nil
+		}
+		steps++
+		seg, _ := p.best(n)
+		return seg.From, seg.Road
+	})
+	if route.Nodes[0] != p.start {
+		return Route{}, ErrNoRoute
+	}
+	route.planned = p.Map.Version()
+	return route, nil
+}
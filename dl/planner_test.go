@@ -0,0 +1,151 @@
+package dl
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"engo.io/engo"
+)
+
+// checkPlanner checks that the planner finds a route as cheap as FindRoute, and that it knows every road
+func checkPlanner(t *testing.T, p *Planner, start, goal *RouteNode) {
+	t.Helper()
+	got, err := p.Route(start, goal)
+	want, wantErr := p.Map.FindRoute(start, goal, p.Cost, p.Heuristic)
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("route from %v to %v: got %v, expected %v", start, goal, err, wantErr)
+	}
+	if err != nil {
+		return
+	}
+	checkRoute(t, got, start, goal)
+	if d := got.Cost(p.Cost) - want.Cost(p.Cost); d > 1e-2 || d < -1e-2 {
+		t.Fatalf("route from %v to %v costs %f, but the cheapest costs %f", start, goal, got.Cost(p.Cost), want.Cost(p.Cost))
+	}
+
+	var roads int
+	for _, n := range p.Map.Nodes {
+		roads += len(n.Roads)
+	}
+	for id, incoming := range p.incoming {
+		roads -= len(incoming)
+		for _, seg := range incoming {
+			if seg.To.ID != id || !p.Map.HasRoad(seg) {
+				t.Fatalf("the planner knows a road from %v to %v which isn't on the map", seg.From, seg.To)
+			}
+		}
+	}
+	if roads != 0 {
+		t.Fatalf("the planner misses %d roads", roads)
+	}
+}
+
+func TestPlannerGoalMoves(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+	p := NewPlanner(m, DistanceCost, DistanceHeuristic)
+	start := m.NearestNode(engo.Point{100, 100})
+	goal := m.NearestNode(engo.Point{500, 500})
+	for _, to := range []engo.Point{{600, 500}, {700, 500}, {700, 600}, {200, 900}, {500, 500}, {100, 100}} {
+		checkPlanner(t, p, start, goal)
+		goal = m.NearestNode(to)
+	}
+}
+
+func TestPlannerStartMoves(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+	p := NewPlanner(m, DistanceCost, DistanceHeuristic)
+	start := m.NearestNode(engo.Point{100, 100})
+	goal := m.NearestNode(engo.Point{800, 700})
+	for _, to := range []engo.Point{{200, 100}, {300, 100}, {300, 200}, {900, 900}, {800, 700}} {
+		checkPlanner(t, p, start, goal)
+		start = m.NearestNode(to)
+	}
+}
+
+func TestPlannerClosedRoads(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+	p := NewPlanner(m, DistanceCost, DistanceHeuristic)
+	start := m.NearestNode(engo.Point{100, 100})
+	goal := m.NearestNode(engo.Point{600, 600})
+	checkPlanner(t, p, start, goal)
+
+	// Like at a car accident, the goal is on a closed road
+	pos := m.SnapToRoad(engo.Point{650, 600})
+	accident := m.AcquireNode(pos)
+	closure := m.CloseRoads(m.IntersectionRoads(accident)...)
+	checkPlanner(t, p, start, accident)
+
+	// Closing the road the unit drives over makes it drive around it
+	route, _ := p.Route(start, accident)
+	blocked := m.CloseRoads(m.SegmentRoads(RoadSegment{route.Nodes[1], route.Nodes[2], route.Roads[2]})...)
+	checkPlanner(t, p, start, accident)
+	checkPlanner(t, p, start, goal)
+
+	m.LiftRestriction(blocked)
+	m.LiftRestriction(closure)
+	m.ReleaseNode(accident)
+	checkPlanner(t, p, start, goal)
+
+	// The start can only be left by its closed roads, and the goal only reached by them
+	m.CloseRoads(m.IntersectionRoads(start)...)
+	m.CloseRoads(m.IntersectionRoads(goal)...)
+	checkPlanner(t, p, start, goal)
+}
+
+func TestPlannerRandom(t *testing.T) {
+	m := RandomMap(15, 15, 100, 100)
+	m.Nodes[20].Roads[0].OneWay = true
+	m.Initialize()
+	rng := rand.New(rand.NewSource(3))
+	random := func() engo.Point { return engo.Point{rng.Float32() * 1500, rng.Float32() * 1500} }
+
+	p := NewPlanner(m, TravelTimeCost(150), TravelTimeHeuristic(150))
+	start, goal := m.Nodes[0], m.Nodes[len(m.Nodes)-1]
+	var (
+		restrictions []*RoadRestriction
+		held         []*RouteNode
+	)
+	for k := 0; k < 2000; k++ {
+		switch rng.Intn(8) {
+		case 0:
+			factor := float32(rng.Intn(2)) * 0.5
+			restrictions = append(restrictions, m.Restrict(factor, m.RoadsAt(m.SnapToRoad(random()))...))
+		case 1:
+			if len(restrictions) > 0 {
+				i := rng.Intn(len(restrictions))
+				m.LiftRestriction(restrictions[i])
+				restrictions = append(restrictions[:i], restrictions[i+1:]...)
+			}
+		case 2:
+			held = append(held, m.AcquireNode(m.SnapToRoad(random())))
+		case 3:
+			if len(held) > 0 {
+				i := rng.Intn(len(held))
+				m.ReleaseNode(held[i])
+				held = append(held[:i], held[i+1:]...)
+			}
+		case 4, 5:
+			// The goal drives on, sometimes to a node which was added for it
+			if road := goal.Roads[rng.Intn(len(goal.Roads))]; rng.Intn(3) > 0 {
+				goal = m.Node(road.To)
+			} else {
+				goal = m.AcquireNode(m.SnapToRoad(random()))
+				held = append(held, goal)
+			}
+		case 6:
+			start = m.Node(start.Roads[rng.Intn(len(start.Roads))].To)
+		}
+
+		if m.Node(start.ID) != start {
+			start = m.NearestNode(start.Location)
+		}
+		if m.Node(goal.ID) != goal {
+			goal = m.NearestNode(goal.Location)
+		}
+		checkPlanner(t, p, start, goal)
+	}
+}
@@ -0,0 +1,202 @@
+package dl
+
+import (
+	"image/color"
+	"math/rand"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+)
+
+// poiMaxRoadDistance is how far a point of interest can be from the nearest road
+const poiMaxRoadDistance = 100
+
+// poiIncidentChance is the chance an incident happens at one of the points of interest relevant to it, if there are any
+const poiIncidentChance = 0.6
+
+// POIKind indicates what a point of interest is
+type POIKind uint8
+
+const (
+	POIStation POIKind = iota
+	POIHospital
+	POIJail
+	POIBank
+	POISchool
+	POIBar
+	POIFireStation
+	POIDepot
+)
+
+var poiKindNames = nameTable[POIKind]{typeName: "POIKind", what: "point of interest kind", names: map[POIKind]string{
+	POIStation:     "station",
+	POIHospital:    "hospital",
+	POIJail:        "jail",
+	POIBank:        "bank",
+	POISchool:      "school",
+	POIBar:         "bar",
+	POIFireStation: "fire_station",
+	POIDepot:       "depot",
+}}
+
+// IncidentPOIs are the kinds of points of interest at which incidents of a type happen more often, by the type of
+// the incident
+var IncidentPOIs = map[string][]POIKind{
+	"IncidentRobbery":     {POIBank},
+	"IncidentCarAccident": {POISchool, POIBar},
+}
+
+func (k POIKind) String() string {
+	return poiKindNames.name(k)
+}
+
+func ParsePOIKind(s string) (POIKind, error) {
+	return poiKindNames.parse(s)
+}
+
+func (k POIKind) MarshalYAML() (interface{}, error) {
+	return k.String(), nil
+}
+
+func (k *POIKind) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return poiKindNames.unmarshalYAML(unmarshal, k)
+}
+
+// POI is a point of interest, like a police station or a bank. It's reached by the road closest to it.
+type POI struct {
+	Name     string     `yaml:"name,omitempty"`
+	Kind     POIKind    `yaml:"kind"`
+	Location engo.Point `yaml:"location"`
+}
+
+func (p *POI) String() string {
+	if p.Name == "" {
+		return "the " + p.Kind.String()
+	}
+	return p.Name
+}
+
+// Entrance returns the position on the road where the point of interest is
+func (m *Map) Entrance(p *POI) RoadPosition {
+	return m.SnapToRoad(p.Location)
+}
+
+// POIsOf returns all points of interest of the kind
+func (m *Map) POIsOf(kind POIKind) []*POI {
+	var pois []*POI
+	for _, poi := range m.POIs {
+		if poi.Kind == kind {
+			pois = append(pois, poi)
+		}
+	}
+	return pois
+}
+
+// NearestPOI returns the point of interest of the kind which a unit driving at most at maxSpeed gets to the fastest
+// from the position, or nil if it can't get to any
+func (m *Map) NearestPOI(from RoadPosition, kind POIKind, maxSpeed float32) *POI {
+	pois := m.POIsOf(kind)
+	if len(pois) == 0 {
+		return nil
+	}
+
+	times := m.TravelTimesFrom(from, maxSpeed)
+	var (
+		nearest *POI
+		best    = unreachable
+	)
+	for _, poi := range pois {
+		if eta, ok := times.ETA(poi.Location); ok && eta < best {
+			nearest, best = poi, eta
+		}
+	}
+	return nearest
+}
+
+// RandomIncidentLocationFor is like RandomIncidentLocation, but favours the points of interest where incidents of
+// the type happen more often, like banks for robberies
+func (m *Map) RandomIncidentLocationFor(incidentType string, rng *rand.Rand) engo.Point {
+	var pois []*POI
+	for _, kind := range IncidentPOIs[incidentType] {
+		pois = append(pois, m.POIsOf(kind)...)
+	}
+	if len(pois) > 0 && rng.Float32() < poiIncidentChance {
+		return m.Entrance(pois[rng.Intn(len(pois))]).Point
+	}
+	return m.RandomIncidentLocation(rng)
+}
+
+// validatePOIs checks whether every point of interest is close enough to a road; the map should have been prepared
+func (m *Map) validatePOIs() MapErrors {
+	var errs MapErrors
+	for _, poi := range m.POIs {
+		pos := m.Entrance(poi)
+		if d := pos.Point.PointDistance(poi.Location); !pos.OnRoad() || d > poiMaxRoadDistance {
+			errs.add(0, "%s at (%v, %v) is not close to any road", poi, poi.Location.X, poi.Location.Y)
+		}
+	}
+	return errs
+}
+
+// POIRenderSystem draws the points of interest of a map, with their names
+type POIRenderSystem struct {
+	Map *Map
+}
+
+func (p *POIRenderSystem) New(w *ecs.World) {
+	fnt := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.White,
+		Size: 24,
+	}
+	if err := fnt.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+
+	var rs *common.RenderSystem
+	for _, system := range w.Systems() {
+		if sys, ok := system.(*common.RenderSystem); ok {
+			rs = sys
+		}
+	}
+	if rs == nil {
+		return
+	}
+
+	for _, poi := range p.Map.POIs {
+		g := &ui.Graphic{
+			BasicEntity: ecs.NewBasic(),
+			RenderComponent: common.RenderComponent{
+				Drawable: ui.POIGraphic,
+				Color:    ui.POIColors[int(poi.Kind)%len(ui.POIColors)],
+			},
+			SpaceComponent: common.SpaceComponent{
+				Position: engo.Point{X: poi.Location.X - ui.POISize/2, Y: poi.Location.Y - ui.POISize/2},
+				Width:    ui.POISize,
+				Height:   ui.POISize,
+			},
+		}
+		g.SetZIndex(3)
+		rs.Add(&g.BasicEntity, &g.RenderComponent, &g.SpaceComponent)
+
+		label := &ui.Label{
+			BasicEntity:     ecs.NewBasic(),
+			Font:            fnt,
+			SpaceComponent:  common.SpaceComponent{Position: engo.Point{X: poi.Location.X + ui.POISize, Y: poi.Location.Y}},
+			RenderComponent: common.RenderComponent{Scale: engo.Point{0.5, 0.5}},
+		}
+		text := poi.Name
+		if text == "" {
+			text = poi.Kind.String()
+		}
+		label.SetText(text)
+		label.SetZIndex(3)
+		rs.Add(&label.BasicEntity, &label.RenderComponent, &label.SpaceComponent)
+	}
+}
+
+func (p *POIRenderSystem) Remove(b ecs.BasicEntity) {}
+
+func (p *POIRenderSystem) Update(dt float32) {}
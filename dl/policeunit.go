@@ -1,11 +1,17 @@
 package dl
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"engo.io/engo"
+	"github.com/BurntSushi/toml"
 	"github.com/luxengine/math"
 	"gopkg.in/yaml.v2"
 )
@@ -18,55 +24,152 @@ const (
 	CommandLookout
 	CommandSearchArea
 	CommandTrafficControl
+	CommandPursue
+	CommandPatrol
+	CommandTransport
 )
 
-type PoliceUnitType struct {
-	Name             string
-	Speed            float32
-	Size             float32 `yaml:"size"`
-	PassengersPolice int     `yaml:"passenges"`
-	PassengersCuffed int     `yaml:"arrested"`
-	PassengersTotal  int     `yaml:"total"`
-	ViewDistance     float32 `yaml:"distance_view"`
+// UnitType is a kind of unit of one of the agencies, like a police car or an ambulance
+type UnitType struct {
+	Name string `yaml:"name" json:"name" toml:"name"`
+	// Agency is the same for all units in a catalog, so it's set by the catalog
+	Agency           Agency       `yaml:"-" json:"-" toml:"-"`
+	Capabilities     Capabilities `yaml:"capabilities" json:"capabilities" toml:"capabilities"`
+	Speed            float32      `yaml:"speed" json:"speed" toml:"speed"`
+	Size             float32      `yaml:"size" json:"size" toml:"size"`
+	PassengersPolice int          `yaml:"passengers" json:"passengers" toml:"passengers"`
+	PassengersCuffed int          `yaml:"arrested" json:"arrested" toml:"arrested"`
+	PassengersTotal  int          `yaml:"total" json:"total" toml:"total"`
+	ViewDistance     float32      `yaml:"distance_view" json:"distance_view" toml:"distance_view"`
 }
 
-type PoliceUnitTypes []PoliceUnitType
+// validate returns every problem with the unit type
+func (t UnitType) validate() []string {
+	var problems []string
+	if t.Name == "" {
+		problems = append(problems, "has no name")
+	}
+	if t.Speed <= 0 {
+		problems = append(problems, fmt.Sprintf("speed must be positive, not %v", t.Speed))
+	}
+	if t.Size <= 0 {
+		problems = append(problems, fmt.Sprintf("size must be positive, not %v", t.Size))
+	}
+	if t.ViewDistance < 0 {
+		problems = append(problems, fmt.Sprintf("distance_view can't be negative, not %v", t.ViewDistance))
+	}
+	if t.PassengersPolice < 0 || t.PassengersCuffed < 0 {
+		problems = append(problems, "passengers and arrested can't be negative")
+	}
+	if t.PassengersPolice+t.PassengersCuffed != t.PassengersTotal {
+		problems = append(problems, fmt.Sprintf("passengers (%d) and arrested (%d) don't add up to total (%d)",
+			t.PassengersPolice, t.PassengersCuffed, t.PassengersTotal))
+	}
+	if t.PassengersCuffed > 0 && !t.Capabilities.Has(CapabilityArrest) {
+		problems = append(problems, "has room for arrested suspects, but can't arrest")
+	}
+	return problems
+}
+
+type UnitTypes []UnitType
+
+// Validate checks every unit type, and whether their names are unique
+func (p UnitTypes) Validate() error {
+	var problems []string
+	names := make(map[string]int)
+	for i, t := range p {
+		for _, problem := range t.validate() {
+			problems = append(problems, fmt.Sprintf("unit %d (%q): %s", i+1, t.Name, problem))
+		}
+		if first, ok := names[t.Name]; ok && t.Name != "" {
+			problems = append(problems, fmt.Sprintf("unit %d (%q): same name as unit %d", i+1, t.Name, first))
+		}
+		names[t.Name] = i + 1
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "\n"))
+}
 
-func (p PoliceUnitTypes) ByName(name string) PoliceUnitType {
+func (p UnitTypes) ByName(name string) (UnitType, error) {
 	for id, police := range p {
 		if police.Name == name {
-			return p[id]
+			return p[id], nil
 		}
 	}
-	return PoliceUnitType{}
+	return UnitType{}, fmt.Errorf("unknown unit type: %q", name)
 }
 
+// PoliceComponent is a unit of any of the agencies, which is dispatched like the police
 type PoliceComponent struct {
 	Location *engo.Point
-	Unit     PoliceUnitType
+	Unit     UnitType
+	// Zone is the zone the unit is assigned to, if any
+	Zone *Zone
+	// Station is where the unit returns to, like a police station or a hospital, if it has one
+	Station *POI
+	// Arrested is the number of suspects the unit is bringing to jail
+	Arrested int
+	// Status is what the unit is doing, as far as the dispatcher is concerned. It's changed using SetStatus.
+	Status        UnitStatus
+	StatusHistory []StatusChange
 
-	// Commands stuff
-	Commands []PoliceCommand
-	Targets  []engo.Point
-
-	CurrentCommand PoliceCommand
-	CurrentTarget  engo.Point
+	// Orders are carried out once the current order is done, first to last
+	Orders         []Order
+	Current        Order
 	CurrentResolve DispatchSystemIncidentEntity
+	// suspended are the orders interrupted by urgent ones, which are carried out again last to first
+	suspended []Order
 
 	// Move-specific info
 	CurrentRoute Route
+
+	// Patrol-specific info
+	patrol *rand.Rand
+
+	// TrafficControl-specific info
+	closure *RoadRestriction
+
+	// scene is the incident the unit last arrived at, and is waiting at for other units
+	scene uint64
+
+	// SearchArea-specific info
+	searchTime float32
+	searched   map[*Road]time.Duration
+
+	// Pursue-specific info
+	pursued DispatchSystemIncidentEntity
+	pursuit *Planner
 }
 
-func LoadPoliceUnits(filename string) (PoliceUnitTypes, error) {
-	ext := filepath.Ext(filename)
-	var unmarshal func([]byte, interface{}) error
+// unitDecoders read unit files by their extension, and fail on any field a unit type doesn't have
+var unitDecoders = map[string]func([]byte, interface{}) error{
+	".yaml": yaml.UnmarshalStrict,
+	".yml":  yaml.UnmarshalStrict,
+	".json": func(b []byte, v interface{}) error {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(v)
+	},
+	".toml": func(b []byte, v interface{}) error {
+		md, err := toml.Decode(string(b), v)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown field %q", undecoded[0].String())
+		}
+		return nil
+	},
+}
 
-	switch ext {
-	case ".yaml":
-		unmarshal = yaml.Unmarshal
-	default:
-		// Ignore
-		return nil, nil
+// LoadUnits reads the catalog of unit types of an agency from a .yaml, .json or .toml file. Fields a unit type doesn't
+// have and unit types that don't make sense are an error.
+func LoadUnits(filename string) (UnitTypes, error) {
+	unmarshal, ok := unitDecoders[filepath.Ext(filename)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported unit file %s: use .yaml, .json or .toml", filename)
 	}
 
 	b, err := ioutil.ReadFile(filename)
@@ -75,32 +178,50 @@ func LoadPoliceUnits(filename string) (PoliceUnitTypes, error) {
 	}
 
 	var units struct {
-		Units []PoliceUnitType
+		Agency *Agency   `yaml:"agency" json:"agency" toml:"agency"`
+		Units  UnitTypes `yaml:"units" json:"units" toml:"units"`
 	}
 
-	err = unmarshal(b, &units)
-	if err != nil {
-		return nil, err
+	if err := unmarshal(b, &units); err != nil {
+		return nil, fmt.Errorf("invalid units %s: %v", filename, err)
+	}
+	if units.Agency == nil {
+		return nil, fmt.Errorf("invalid units %s: no agency", filename)
+	}
+	for i := range units.Units {
+		units.Units[i].Agency = *units.Agency
+	}
+	if err := units.Units.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid units %s:\n%v", filename, err)
 	}
 
 	return units.Units, nil
 }
 
-func (p *PoliceComponent) QueueCommand(c PoliceCommand, target engo.Point) {
-	p.Commands = append(p.Commands, c)
-	p.Targets = append(p.Targets, target)
-}
+// LoadUnitCatalogs reads the catalogs of all agencies in the directory, which holds one file per agency
+func LoadUnitCatalogs(dir string) (UnitTypes, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
 
-func (p *PoliceComponent) processCommand() (PoliceCommand, engo.Point) {
-	if len(p.Commands) == 0 {
-		return CommandHold, engo.Point{}
+	var all UnitTypes
+	for _, file := range files {
+		if _, ok := unitDecoders[filepath.Ext(file.Name())]; !ok || file.IsDir() {
+			continue
+		}
+		units, err := LoadUnits(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, units...)
 	}
 
-	cmd := p.Commands[0]
-	p.Commands = p.Commands[1:]
-	target := p.Targets[0]
-	p.Targets = p.Targets[1:]
-	return cmd, target
+	// Names have to be unique over all agencies as well
+	if err := all.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid units in %s:\n%v", dir, err)
+	}
+	return all, nil
 }
 
 func (p *PoliceComponent) Update(dt float32) {
@@ -109,7 +230,11 @@ func (p *PoliceComponent) Update(dt float32) {
 
 // Move allows the unit to move to the set destination, at the speed of the update
 func (p *PoliceComponent) Move(dt float32) {
-	var distance = p.Unit.Speed / 3.6 * dt
+	speed := p.Unit.Speed
+	if road := p.CurrentRoute.Roads[0]; road != nil {
+		speed = road.Speed(speed)
+	}
+	var distance = speed / 3.6 * dt
 
 	target := p.CurrentRoute.Nodes[0].Location
 
@@ -126,8 +251,9 @@ func (p *PoliceComponent) Move(dt float32) {
 		movementX = dx
 		movementY = dy
 		p.CurrentRoute.Nodes = p.CurrentRoute.Nodes[1:]
+		p.CurrentRoute.Roads = p.CurrentRoute.Roads[1:]
 		if len(p.CurrentRoute.Nodes) == 0 {
-			p.CurrentCommand = CommandHold
+			p.Current.Command = CommandHold
 		} else {
 			fmt.Println(len(p.CurrentRoute.Nodes))
 		}
@@ -136,7 +262,3 @@ func (p *PoliceComponent) Move(dt float32) {
 	p.Location.X += movementX
 	p.Location.Y += movementY
 }
-
-func (p *PoliceComponent) Wander(dt float32, location engo.Point) {
-	fmt.Println("TODO: wander behavior")
-}
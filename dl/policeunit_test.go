@@ -0,0 +1,142 @@
+package dl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the files to a new directory, which is removed once the test is done
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadUnitCatalogs(t *testing.T) {
+	units, err := LoadUnitCatalogs("../assets/units")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, agency := range map[string]Agency{
+		"Car":         AgencyPolice,
+		"Ambulance":   AgencyMedical,
+		"Fire Engine": AgencyFire,
+		"Tow Truck":   AgencyTowing,
+	} {
+		unit, err := units.ByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unit.Agency != agency {
+			t.Fatalf("%s belongs to the %s agency, expected %s", name, unit.Agency, agency)
+		}
+	}
+	if _, err := units.ByName("Tank"); err == nil || !strings.Contains(err.Error(), "Tank") {
+		t.Fatalf("found an unknown unit type, or didn't say which: %v", err)
+	}
+
+	// Names are unique over all agencies
+	dir := writeFiles(t, map[string]string{
+		"police.yaml":  "agency: police\nunits:\n  - {name: Car, speed: 1, size: 1, passengers: 2, total: 2}\n",
+		"towing.json":  `{"agency": "towing", "units": [{"name": "Car", "speed": 1, "size": 1}]}`,
+		"README.txt":   "Files of other types are skipped",
+		"medical.toml": "agency = \"medical\"\n\n[[units]]\nname = \"Ambulance\"\nspeed = 1\nsize = 1\n",
+	})
+	if _, err := LoadUnitCatalogs(dir); err == nil || !strings.Contains(err.Error(), "same name") {
+		t.Fatalf("loaded two unit types with the same name: %v", err)
+	}
+}
+
+func TestLoadUnits(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+		// problem is part of the error, or empty if the file is valid
+		problem string
+	}{
+		{"YAML", "units.yaml", "agency: fire\nunits:\n  - name: Ladder\n    capabilities: [fire]\n    speed: 100\n" +
+			"    size: 2\n    passengers: 4\n    total: 4\n", ""},
+		{"JSON", "units.json", `{"agency": "fire", "units": [{"name": "Ladder", "capabilities": ["fire"], ` +
+			`"speed": 100, "size": 2, "passengers": 4, "total": 4}]}`, ""},
+		{"TOML", "units.toml", "agency = \"fire\"\n\n[[units]]\nname = \"Ladder\"\nspeed = 100\nsize = 2\n" +
+			"passengers = 4\ntotal = 4\n", ""},
+		{"unknown extension", "units.xml", "<units/>", "unsupported"},
+		{"unknown YAML field", "units.yaml", "agency: fire\nunits:\n  - {name: Ladder, speed: 1, size: 1, ladder: 30}\n",
+			"ladder"},
+		{"unknown JSON field", "units.json", `{"agency": "fire", "units": [{"name": "Ladder", "speed": 1, "size": 1, ` +
+			`"ladder": 30}]}`, "ladder"},
+		{"unknown TOML field", "units.toml", "agency = \"fire\"\n\n[[units]]\nname = \"Ladder\"\nspeed = 1\n" +
+			"size = 1\nladder = 30\n", "ladder"},
+		{"no agency", "units.yaml", "units:\n  - {name: Ladder, speed: 1, size: 1}\n", "no agency"},
+		{"unknown agency", "units.yaml", "agency: navy\nunits: []\n", "navy"},
+		{"unknown capability", "units.json", `{"agency": "fire", "units": [{"name": "Ladder", ` +
+			`"capabilities": ["flying"], "speed": 1, "size": 1}]}`, "flying"},
+		{"passengers don't add up", "units.yaml", "agency: police\nunits:\n" +
+			"  - {name: Car, capabilities: [arrest], speed: 1, size: 1, passengers: 4, arrested: 2, total: 5}\n",
+			"don't add up to total (5)"},
+		{"same name", "units.yaml", "agency: police\nunits:\n  - {name: Car, speed: 1, size: 1}\n" +
+			"  - {name: Car, speed: 2, size: 1}\n", "same name as unit 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{tt.file: tt.content})
+			units, err := LoadUnits(filepath.Join(dir, tt.file))
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatal(err)
+			case tt.problem != "" && err == nil:
+				t.Fatalf("loaded the units, expected an error about %q", tt.problem)
+			case err != nil && !strings.Contains(err.Error(), tt.problem):
+				t.Fatalf("error %q doesn't mention %q", err, tt.problem)
+			case err == nil && (len(units) != 1 || units[0].Agency != AgencyFire || units[0].PassengersTotal != 4):
+				t.Fatalf("loaded %+v", units)
+			}
+		})
+	}
+}
+
+func TestUnitTypesValidate(t *testing.T) {
+	units := UnitTypes{
+		{Name: "Car", Capabilities: Capabilities{CapabilityArrest}, Speed: 1, Size: 1, PassengersPolice: 4,
+			PassengersCuffed: 2, PassengersTotal: 6},
+		{Name: "", Speed: 0, Size: -1, ViewDistance: -1},
+		{Name: "Ambulance", Speed: 1, Size: 1, PassengersCuffed: 1, PassengersTotal: 1},
+		{Name: "Car", Speed: 1, Size: 1},
+	}
+	err := units.Validate()
+	if err == nil {
+		t.Fatal("invalid unit types were accepted")
+	}
+	for _, problem := range []string{
+		`unit 2 (""): has no name`,
+		`unit 2 (""): speed must be positive`,
+		`unit 2 (""): size must be positive`,
+		`unit 2 (""): distance_view can't be negative`,
+		`unit 3 ("Ambulance"): has room for arrested suspects, but can't arrest`,
+		`unit 4 ("Car"): same name as unit 1`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("%q isn't reported in:\n%v", problem, err)
+		}
+	}
+	if strings.Contains(err.Error(), "unit 1 ") {
+		t.Errorf("the valid unit is reported in:\n%v", err)
+	}
+
+	if err := units[:1].Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,25 +1,177 @@
 package dl
 
-import "sort"
+// PriorityQueue is a binary min-heap: the item with the lowest priority is dequeued first. Items with the same
+// priority are dequeued in the order they were enqueued. Every item can be in the queue at most once, which allows
+// changing its priority afterwards.
+type PriorityQueue[T comparable] struct {
+	items   []queueEntry[T]
+	indices map[T]int
+	counter uint64
+}
+
+type queueEntry[T comparable] struct {
+	item     T
+	priority float32
+	seq      uint64
+}
 
-type PriorityQueue struct {
-	list   []interface{}
-	values []float32
+// Len returns the number of items in the queue
+func (p *PriorityQueue[T]) Len() int {
+	return len(p.items)
 }
 
-func (p *PriorityQueue) Enqueue(item interface{}, value float32) {
-	index := SearchFloat32s(p.values, value)
-	p.values = append(p.values[:index], append([]float32{value}, p.values[index:]...)...)
-	p.list = append(p.list[:index], append([]interface{}{item}, p.list[index:]...)...)
+// Enqueue adds the item with the given priority. An item which is already queued isn't added a second time: its
+// priority is updated instead, as with Update, and it keeps its place among the items of the same priority.
+func (p *PriorityQueue[T]) Enqueue(item T, priority float32) {
+	if p.Update(item, priority) {
+		return
+	}
+	if p.indices == nil {
+		p.indices = make(map[T]int)
+	}
+
+	p.counter++
+	p.items = append(p.items, queueEntry[T]{item: item, priority: priority, seq: p.counter})
+	p.indices[item] = len(p.items) - 1
+	p.up(len(p.items) - 1)
 }
 
-func (p *PriorityQueue) Dequeue() interface{} {
-	p.values = p.values[1:]
-	item := p.list[0]
-	p.list = p.list[1:]
+// Dequeue removes and returns the item with the lowest priority. It panics if the queue is empty; use TryDequeue
+// if that may be the case.
+func (p *PriorityQueue[T]) Dequeue() T {
+	item, _, ok := p.TryDequeue()
+	if !ok {
+		panic("dl: Dequeue called on an empty PriorityQueue")
+	}
 	return item
 }
 
-func SearchFloat32s(a []float32, x float32) int {
-	return sort.Search(len(a), func(i int) bool { return a[i] >= x })
+// TryDequeue removes and returns the item with the lowest priority, and false if the queue is empty
+func (p *PriorityQueue[T]) TryDequeue() (T, float32, bool) {
+	if len(p.items) == 0 {
+		var zero T
+		return zero, 0, false
+	}
+
+	first := p.items[0]
+	p.removeAt(0)
+	return first.item, first.priority, true
+}
+
+// Peek returns the item with the lowest priority without removing it, and false if the queue is empty
+func (p *PriorityQueue[T]) Peek() (T, float32, bool) {
+	if len(p.items) == 0 {
+		var zero T
+		return zero, 0, false
+	}
+	return p.items[0].item, p.items[0].priority, true
+}
+
+// Contains indicates whether or not the item is queued
+func (p *PriorityQueue[T]) Contains(item T) bool {
+	_, ok := p.indices[item]
+	return ok
+}
+
+// Priority returns the priority of the item, and false if it is not queued
+func (p *PriorityQueue[T]) Priority(item T) (float32, bool) {
+	i, ok := p.indices[item]
+	if !ok {
+		return 0, false
+	}
+	return p.items[i].priority, true
+}
+
+// Update changes the priority of an item that is already queued. It returns false if the item is not queued.
+func (p *PriorityQueue[T]) Update(item T, priority float32) bool {
+	i, ok := p.indices[item]
+	if !ok {
+		return false
+	}
+
+	old := p.items[i].priority
+	p.items[i].priority = priority
+	if priority < old {
+		p.up(i)
+	} else {
+		p.down(i)
+	}
+	return true
+}
+
+// Remove removes the item from the queue. It returns false if the item is not queued.
+func (p *PriorityQueue[T]) Remove(item T) bool {
+	i, ok := p.indices[item]
+	if !ok {
+		return false
+	}
+	p.removeAt(i)
+	return true
+}
+
+// Items returns all queued items, in no particular order
+func (p *PriorityQueue[T]) Items() []T {
+	items := make([]T, len(p.items))
+	for i, e := range p.items {
+		items[i] = e.item
+	}
+	return items
+}
+
+func (p *PriorityQueue[T]) removeAt(i int) {
+	last := len(p.items) - 1
+	delete(p.indices, p.items[i].item)
+	if i != last {
+		p.items[i] = p.items[last]
+		p.indices[p.items[i].item] = i
+	}
+	p.items[last] = queueEntry[T]{}
+	p.items = p.items[:last]
+
+	if i != last {
+		p.down(i)
+		p.up(i)
+	}
+}
+
+func (p *PriorityQueue[T]) less(i, j int) bool {
+	if p.items[i].priority == p.items[j].priority {
+		return p.items[i].seq < p.items[j].seq
+	}
+	return p.items[i].priority < p.items[j].priority
+}
+
+func (p *PriorityQueue[T]) swap(i, j int) {
+	p.items[i], p.items[j] = p.items[j], p.items[i]
+	p.indices[p.items[i].item] = i
+	p.indices[p.items[j].item] = j
+}
+
+func (p *PriorityQueue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !p.less(i, parent) {
+			return
+		}
+		p.swap(i, parent)
+		i = parent
+	}
+}
+
+func (p *PriorityQueue[T]) down(i int) {
+	n := len(p.items)
+	for {
+		smallest := i
+		if l := 2*i + 1; l < n && p.less(l, smallest) {
+			smallest = l
+		}
+		if r := 2*i + 2; r < n && p.less(r, smallest) {
+			smallest = r
+		}
+		if smallest == i {
+			return
+		}
+		p.swap(i, smallest)
+		i = smallest
+	}
 }
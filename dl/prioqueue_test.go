@@ -0,0 +1,170 @@
+package dl
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func dequeueAll(q *PriorityQueue[string]) []string {
+	var items []string
+	for {
+		item, _, ok := q.TryDequeue()
+		if !ok {
+			return items
+		}
+		items = append(items, item)
+	}
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(q *PriorityQueue[string])
+		want  []string
+	}{
+		{"empty", func(q *PriorityQueue[string]) {}, nil},
+		{"by priority", func(q *PriorityQueue[string]) {
+			q.Enqueue("c", 3)
+			q.Enqueue("a", 1)
+			q.Enqueue("b", 2)
+		}, []string{"a", "b", "c"}},
+		{"same priority in order", func(q *PriorityQueue[string]) {
+			q.Enqueue("a", 1)
+			q.Enqueue("b", 1)
+			q.Enqueue("c", 0)
+			q.Enqueue("d", 1)
+		}, []string{"c", "a", "b", "d"}},
+		{"enqueue twice", func(q *PriorityQueue[string]) {
+			q.Enqueue("a", 1)
+			q.Enqueue("b", 2)
+			q.Enqueue("a", 3)
+		}, []string{"b", "a"}},
+		{"enqueue twice keeps its place", func(q *PriorityQueue[string]) {
+			q.Enqueue("a", 5)
+			q.Enqueue("b", 1)
+			q.Enqueue("a", 1)
+		}, []string{"a", "b"}},
+		{"update", func(q *PriorityQueue[string]) {
+			q.Enqueue("a", 1)
+			q.Enqueue("b", 2)
+			q.Enqueue("c", 3)
+			q.Update("c", 0)
+			q.Update("a", 4)
+		}, []string{"c", "b", "a"}},
+		{"remove", func(q *PriorityQueue[string]) {
+			q.Enqueue("a", 1)
+			q.Enqueue("b", 2)
+			q.Enqueue("c", 3)
+			q.Enqueue("d", 4)
+			q.Remove("a")
+			q.Remove("c")
+		}, []string{"b", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q PriorityQueue[string]
+			tt.build(&q)
+			if q.Len() != len(tt.want) {
+				t.Fatalf("%d items, expected %d", q.Len(), len(tt.want))
+			}
+			if got := dequeueAll(&q); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriorityQueueMissing(t *testing.T) {
+	var q PriorityQueue[string]
+	if _, _, ok := q.TryDequeue(); ok {
+		t.Fatal("dequeued from an empty queue")
+	}
+	if _, _, ok := q.Peek(); ok {
+		t.Fatal("peeked into an empty queue")
+	}
+	if q.Update("a", 1) || q.Remove("a") || q.Contains("a") {
+		t.Fatal("found an item that was never queued")
+	}
+
+	q.Enqueue("a", 1)
+	if item, priority, ok := q.TryDequeue(); !ok || item != "a" || priority != 1 {
+		t.Fatal(item, priority, ok)
+	}
+	if q.Update("a", 1) || q.Remove("a") || q.Len() != 0 {
+		t.Fatal("found an item that was dequeued")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Dequeue on an empty queue didn't panic")
+		}
+	}()
+	q.Dequeue()
+}
+
+// TestPriorityQueueRandom compares the queue with a map of the priorities of the queued items
+func TestPriorityQueueRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	var q PriorityQueue[int]
+	queued := make(map[int]float32)
+	for k := 0; k < 20000; k++ {
+		item := rng.Intn(500)
+		switch rng.Intn(5) {
+		case 0, 1:
+			priority := float32(rng.Intn(100))
+			q.Enqueue(item, priority)
+			queued[item] = priority
+		case 2:
+			_, ok := queued[item]
+			if priority := float32(rng.Intn(100)); q.Update(item, priority) != ok {
+				t.Fatal("Update of", item, "doesn't match whether it's queued")
+			} else if ok {
+				queued[item] = priority
+			}
+		case 3:
+			item, priority, ok := q.TryDequeue()
+			if !ok {
+				if len(queued) != 0 {
+					t.Fatal("the queue is empty, but", len(queued), "items were queued")
+				}
+				continue
+			}
+			for _, other := range queued {
+				if other < priority {
+					t.Fatal("dequeued", priority, "before", other)
+				}
+			}
+			if queued[item] != priority {
+				t.Fatal("dequeued", item, "with priority", priority, "but it was", queued[item])
+			}
+			delete(queued, item)
+		case 4:
+			_, ok := queued[item]
+			if q.Remove(item) != ok {
+				t.Fatal("Remove of", item, "doesn't match whether it's queued")
+			}
+			delete(queued, item)
+		}
+		if q.Len() != len(queued) {
+			t.Fatalf("%d items, expected %d", q.Len(), len(queued))
+		}
+	}
+}
+
+func BenchmarkPriorityQueue(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var q PriorityQueue[int]
+		for k := 0; k < 10000; k++ {
+			q.Enqueue(k, float32((k*7919)%10007))
+		}
+		// Like A*, lower the priority of some of the queued items
+		for k := 0; k < 10000; k += 3 {
+			q.Update(k, float32((k*7919)%10007)/2)
+		}
+		for q.Len() > 0 {
+			q.Dequeue()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package dl
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+	"unicode"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+)
+
+// radioLogLines is the number of radio messages that are shown
+const radioLogLines = 6
+
+// RadioMessage is said over the radio, which is shown in the radio log
+type RadioMessage struct {
+	Text string
+}
+
+func (RadioMessage) Type() string { return "RadioMessage" }
+
+// Radio says something over the radio
+func Radio(format string, args ...interface{}) {
+	engo.Mailbox.Dispatch(RadioMessage{fmt.Sprintf(format, args...)})
+}
+
+// RadioLogSystem shows the last radio messages in the corner, below the incident counter
+type RadioLogSystem struct {
+	lines []ui.Label
+	log   []string
+}
+
+func (r *RadioLogSystem) New(w *ecs.World) {
+	fnt := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.White,
+		Size: 28,
+	}
+	if err := fnt.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+
+	r.lines = make([]ui.Label, radioLogLines)
+	for i := range r.lines {
+		r.lines[i] = ui.Label{
+			BasicEntity:     ecs.NewBasic(),
+			Font:            fnt,
+			SpaceComponent:  common.SpaceComponent{engo.Point{4, 32 + 16*float32(i)}, 400, 16, 0},
+			RenderComponent: common.RenderComponent{Scale: engo.Point{0.5, 0.5}},
+		}
+		r.lines[i].SetText(" ")
+		r.lines[i].RenderComponent.SetShader(common.HUDShader)
+	}
+
+	for _, system := range w.Systems() {
+		switch sys := system.(type) {
+		case *common.RenderSystem:
+			for i := range r.lines {
+				sys.Add(&r.lines[i].BasicEntity, &r.lines[i].RenderComponent, &r.lines[i].SpaceComponent)
+			}
+		}
+	}
+
+	engo.Mailbox.Listen("RadioMessage", func(m engo.Message) {
+		msg := m.(RadioMessage)
+		log.Println("Radio:", msg.Text)
+
+		r.log = append(r.log, msg.Text)
+		if len(r.log) > radioLogLines {
+			r.log = r.log[len(r.log)-radioLogLines:]
+		}
+		for i := range r.lines {
+			text := " "
+			if i < len(r.log) {
+				text = r.log[i]
+			}
+			r.lines[i].SetText(text)
+		}
+	})
+}
+
+func (r *RadioLogSystem) Remove(b ecs.BasicEntity) {}
+
+func (r *RadioLogSystem) Update(dt float32) {}
+
+// incidentName turns the type of an incident into words, e.g. IncidentCarAccident into "Car accident"
+func incidentName(t string) string {
+	t = strings.TrimPrefix(t, "Incident")
+	var words []string
+	start := 0
+	for i, c := range t {
+		if i > start && unicode.IsUpper(c) {
+			words = append(words, t[start:i])
+			start = i
+		}
+	}
+	words = append(words, t[start:])
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToLower(words[i])
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,175 @@
+package dl
+
+// RoadRestriction closes or slows down roads until it's lifted. Restrictions are not part of the map definition,
+// they only exist while playing.
+type RoadRestriction struct {
+	// SpeedFactor is multiplied with the speed on the roads; a factor of 0 closes them
+	SpeedFactor float32
+
+	roads []*Road
+}
+
+// CloseRoads closes the roads until the returned restriction is lifted
+func (m *Map) CloseRoads(roads ...*Road) *RoadRestriction {
+	return m.Restrict(0, roads...)
+}
+
+// Restrict multiplies the speed on the roads with the factor until the returned restriction is lifted. A factor of 0
+// closes the roads. If a road has multiple restrictions, the strictest one applies.
+func (m *Map) Restrict(factor float32, roads ...*Road) *RoadRestriction {
+	r := &RoadRestriction{SpeedFactor: factor}
+	m.version++
+	for _, road := range roads {
+		r.add(road)
+		m.roadChanged(nil, nil, road)
+	}
+	return r
+}
+
+// LiftRestriction reopens the roads, or lets traffic drive at full speed again
+func (m *Map) LiftRestriction(r *RoadRestriction) {
+	if r == nil {
+		return
+	}
+
+	m.version++
+	for _, road := range r.roads {
+		for i, other := range road.restrictions {
+			if other == r {
+				road.restrictions = append(road.restrictions[:i:i], road.restrictions[i+1:]...)
+				break
+			}
+		}
+		m.roadChanged(nil, nil, road)
+	}
+	r.roads = nil
+}
+
+func (r *RoadRestriction) add(road *Road) {
+	r.roads = append(r.roads, road)
+	road.restrictions = append(road.restrictions, r)
+}
+
+// inheritRestrictions makes the road (which replaces the given ones) restricted by everything they were
+// restricted by
+func (r *Road) inheritRestrictions(from ...*Road) {
+	r.restrictions = nil
+	for _, old := range from {
+		for _, restriction := range old.restrictions {
+			if !r.RestrictedBy(restriction) {
+				restriction.add(r)
+			}
+		}
+	}
+}
+
+// RestrictedBy indicates whether or not the restriction applies to the road
+func (r *Road) RestrictedBy(restriction *RoadRestriction) bool {
+	for _, other := range r.restrictions {
+		if other == restriction {
+			return true
+		}
+	}
+	return false
+}
+
+// Closed indicates whether or not the road is closed, in which case no routes will use it
+func (r *Road) Closed() bool {
+	return len(r.restrictions) > 0 && r.SpeedFactor() == 0
+}
+
+// SpeedFactor returns the strictest speed factor of all restrictions of the road, or 1 if there are none
+func (r *Road) SpeedFactor() float32 {
+	factor := float32(1)
+	for _, restriction := range r.restrictions {
+		if restriction.SpeedFactor < factor {
+			factor = restriction.SpeedFactor
+		}
+	}
+	return factor
+}
+
+// IntersectionRoads returns all roads leading to and from the node
+func (m *Map) IntersectionRoads(n *RouteNode) []*Road {
+	roads := append([]*Road(nil), n.Roads...)
+	for _, seg := range m.incomingRoads(n) {
+		roads = append(roads, seg.Road)
+	}
+	return roads
+}
+
+// SegmentRoads returns the road of the segment, and the road going in the opposite direction if there is one
+func (m *Map) SegmentRoads(seg RoadSegment) []*Road {
+	roads := []*Road{seg.Road}
+	if reverse := seg.To.Road(seg.From.ID); reverse != nil {
+		roads = append(roads, reverse)
+	}
+	return roads
+}
+
+// RoadsAt returns the roads at the position: those of the intersection if it's at a node, or those of the segment
+// it's on otherwise
+func (m *Map) RoadsAt(pos RoadPosition) []*Road {
+	switch {
+	case !pos.OnRoad():
+		return nil
+	case pos.Offset <= 0:
+		return m.IntersectionRoads(pos.From)
+	case pos.Offset >= pos.Road.Length:
+		return m.IntersectionRoads(pos.To)
+	default:
+		return m.SegmentRoads(pos.RoadSegment)
+	}
+}
+
+// liftAllRestrictions removes every restriction from the map
+func (m *Map) liftAllRestrictions() {
+	m.version++
+	for _, node := range m.Nodes {
+		for _, road := range node.Roads {
+			road.restrictions = nil
+			m.roadChanged(nil, nil, road)
+		}
+	}
+}
+
+// NeedsReplanning indicates whether any road of the route changed since the route was planned, for example
+// because it was closed, slowed down or split
+func (m *Map) NeedsReplanning(route *Route) bool {
+	if route.planned == m.version {
+		return false
+	}
+	for _, road := range route.Roads {
+		if road != nil && road.changed > route.planned {
+			return true
+		}
+	}
+	route.planned = m.version
+	return false
+}
+
+// Replan plans the rest of the route again, after the node it's currently heading to. The road leading to that
+// node is kept, so whoever follows the route can finish driving on it.
+func (m *Map) Replan(route Route, cost EdgeCost, h Heuristic) (Route, error) {
+	if len(route.Nodes) == 0 {
+		return route, ErrNoRoute
+	}
+
+	next, dest := route.Nodes[0], route.Nodes[len(route.Nodes)-1]
+	if m.Node(dest.ID) != dest {
+		return route, ErrNoRoute
+	}
+	if m.Node(next.ID) != next {
+		// The node was removed, continue from its nearest node instead
+		next = m.NearestNode(next.Location)
+	}
+
+	replanned, err := m.FindRoute(next, dest, cost, h)
+	if err != nil {
+		return route, err
+	}
+	if next == route.Nodes[0] {
+		replanned.Roads[0] = route.Roads[0]
+	}
+	return replanned, nil
+}
@@ -0,0 +1,70 @@
+package dl
+
+import (
+	"testing"
+
+	"engo.io/engo"
+)
+
+func TestCloseRoadAvoided(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	lengths := roadLengths(m)
+	// Routes may leave a closed road they start on, so they start one intersection away from it
+	from, to := m.NearestNode(engo.Point{100, 100}), m.NearestNode(engo.Point{100, 300})
+
+	// Closing a road acquires its position once for driving there, and once for closing it
+	pos := m.SnapToRoad(engo.Point{110, 150})
+	move, control := m.AcquireNode(pos), m.AcquireNode(pos)
+	if move != control {
+		t.Fatal("acquired different nodes for the same road")
+	}
+	closure := m.CloseRoads(m.IntersectionRoads(control)...)
+	m.ReleaseNode(move)
+
+	for _, ends := range [][2]*RouteNode{{from, to}, {to, from}} {
+		route, err := m.FindRoute(ends[0], ends[1], DistanceCost, DistanceHeuristic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range route.Nodes {
+			if n == control {
+				t.Fatal("route drives through the closed road")
+			}
+		}
+		if len(route.Nodes) != 5 {
+			t.Fatalf("route has %d nodes, expected a detour of 5", len(route.Nodes))
+		}
+	}
+
+	m.LiftRestriction(closure)
+	m.ReleaseNode(control)
+	checkRestored(t, m, 9, lengths)
+	route, err := m.FindRoute(from, to, DistanceCost, DistanceHeuristic)
+	if err != nil || len(route.Nodes) != 3 {
+		t.Fatal(route.Nodes, err)
+	}
+}
+
+func TestRestrictionSplitJoin(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	from, to := m.NearestNode(engo.Point{100, 100}), m.NearestNode(engo.Point{100, 200})
+	seg := RoadSegment{from, to, from.Road(to.ID)}
+
+	r := m.Restrict(0.5, m.SegmentRoads(seg)...)
+	tmp := m.AcquireNode(m.PositionAt(seg, seg.Road.Length/3))
+	for _, road := range m.IntersectionRoads(tmp) {
+		if road.SpeedFactor() != 0.5 {
+			t.Fatal("the split roads lost the restriction")
+		}
+	}
+	m.ReleaseNode(tmp)
+	if from.Road(to.ID).SpeedFactor() != 0.5 || to.Road(from.ID).SpeedFactor() != 0.5 {
+		t.Fatal("the joined roads lost the restriction")
+	}
+	m.LiftRestriction(r)
+	if from.Road(to.ID).SpeedFactor() != 1 || to.Road(from.ID).SpeedFactor() != 1 {
+		t.Fatal("the restriction wasn't lifted")
+	}
+}
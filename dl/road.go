@@ -0,0 +1,94 @@
+package dl
+
+// RoadClass indicates what kind of road an edge is, which determines its default speed limit
+type RoadClass uint8
+
+const (
+	RoadResidential RoadClass = iota
+	RoadAlley
+	RoadArterial
+	RoadHighway
+)
+
+var roadClassNames = nameTable[RoadClass]{typeName: "RoadClass", what: "road class", names: map[RoadClass]string{
+	RoadResidential: "residential",
+	RoadAlley:       "alley",
+	RoadArterial:    "arterial",
+	RoadHighway:     "highway",
+}}
+
+// DefaultSpeedLimit is the speed limit (in the same unit as UnitType.Speed) used for roads without an explicit
+// speed limit
+func (c RoadClass) DefaultSpeedLimit() float32 {
+	switch c {
+	case RoadHighway:
+		return 200
+	case RoadArterial:
+		return 140
+	case RoadAlley:
+		return 40
+	default:
+		return 90
+	}
+}
+
+func (c RoadClass) String() string {
+	return roadClassNames.name(c)
+}
+
+func ParseRoadClass(s string) (RoadClass, error) {
+	return roadClassNames.parse(s)
+}
+
+func (c RoadClass) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+func (c *RoadClass) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return roadClassNames.unmarshalYAML(unmarshal, c)
+}
+
+// Road is a directed edge in the road graph, going from the RouteNode it belongs to, to the RouteNode `To`
+type Road struct {
+	To         uint32    `yaml:"to"`
+	Class      RoadClass `yaml:"class"`
+	Length     float32   `yaml:"length,omitempty"`
+	SpeedLimit float32   `yaml:"speedLimit,omitempty"`
+	OneWay     bool      `yaml:"oneWay,omitempty"`
+	Name       string    `yaml:"name,omitempty"`
+	// Numbers are the house numbers along the road, from its start to its end
+	Numbers HouseNumbers `yaml:"numbers,omitempty"`
+
+	restrictions []*RoadRestriction
+	traffic      float32
+	// whole is the length of the road before it was split by temporary nodes, as adding up the lengths of the
+	// parts doesn't always give exactly the same length again
+	whole float32
+	// changed is the version of the map in which the road was last changed
+	changed uint64
+	// trafficChanged is the traffic version of the map in which the traffic on the road last changed
+	trafficChanged uint64
+}
+
+// Speed returns the speed at which a unit with the given maximum speed can drive on this road. A road without
+// a maximum speed of its own uses the default of its class. Traffic and roads which are slowed down are slower, but
+// closed roads are not: whoever is still on them can leave at normal speed.
+func (r *Road) Speed(maxSpeed float32) float32 {
+	speed := r.SpeedLimit
+	if speed <= 0 {
+		speed = r.Class.DefaultSpeedLimit()
+	}
+	if maxSpeed > 0 && maxSpeed < speed {
+		speed = maxSpeed
+	}
+	if factor := r.SpeedFactor(); factor > 0 {
+		speed *= factor
+	}
+	return speed * r.Traffic()
+}
+
+// TravelTime returns the time (in seconds) it takes a unit with the given maximum speed to drive the length of
+// the road
+func (r *Road) TravelTime(maxSpeed float32) float32 {
+	return r.Length / (r.Speed(maxSpeed) / 3.6)
+}
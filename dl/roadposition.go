@@ -0,0 +1,74 @@
+package dl
+
+import (
+	"engo.io/engo"
+)
+
+// RoadPosition is a position somewhere along a road
+type RoadPosition struct {
+	RoadSegment
+
+	// Offset is the distance from the start of the road
+	Offset float32
+	// Point is the actual location on the road
+	Point engo.Point
+}
+
+// OnRoad indicates whether or not the position is on a road, which it's not when snapping on a map without roads
+func (rp RoadPosition) OnRoad() bool {
+	return rp.Road != nil
+}
+
+// Fraction returns how far along the road the position is, between 0 and 1
+func (rp RoadPosition) Fraction() float32 {
+	if rp.Road == nil || rp.Road.Length == 0 {
+		return 0
+	}
+	return rp.Offset / rp.Road.Length
+}
+
+// SnapToRoad returns the position on the road closest to the given point. If the map has no roads, the position
+// isn't on a road, and Point is the nearest node (or the point itself, if there are no nodes either).
+func (m *Map) SnapToRoad(p engo.Point) RoadPosition {
+	seg, ok := m.NearestRoad(p)
+	if !ok {
+		if nearest := m.NearestNode(p); nearest != nil {
+			return RoadPosition{Point: nearest.Location}
+		}
+		return RoadPosition{Point: p}
+	}
+
+	return m.roadPosition(seg, p)
+}
+
+func (m *Map) roadPosition(seg RoadSegment, p engo.Point) RoadPosition {
+	closest, fraction := seg.Closest(p)
+	return RoadPosition{
+		RoadSegment: seg,
+		Offset:      fraction * seg.Road.Length,
+		Point:       closest,
+	}
+}
+
+// PositionAt returns the position at the given distance from the start of the road
+func (m *Map) PositionAt(seg RoadSegment, offset float32) RoadPosition {
+	var fraction float32
+	if seg.Road.Length > 0 {
+		fraction = offset / seg.Road.Length
+	}
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+
+	from, to := seg.From.Location, seg.To.Location
+	return RoadPosition{
+		RoadSegment: seg,
+		Offset:      fraction * seg.Road.Length,
+		Point: engo.Point{
+			X: from.X + (to.X-from.X)*fraction,
+			Y: from.Y + (to.Y-from.Y)*fraction,
+		},
+	}
+}
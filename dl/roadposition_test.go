@@ -0,0 +1,87 @@
+package dl
+
+import (
+	"testing"
+
+	"engo.io/engo"
+)
+
+func TestSnapToRoad(t *testing.T) {
+	// A two-way road from (0, 0) to (100, 0), and a one-way road from (100, 0) to (100, 100)
+	m := &Map{Nodes: []*RouteNode{
+		{ID: 1, Location: engo.Point{X: 0, Y: 0}, ConnectedTo: []uint32{2}},
+		{ID: 2, Location: engo.Point{X: 100, Y: 0}, ConnectedTo: []uint32{1}},
+		{ID: 3, Location: engo.Point{X: 100, Y: 100}},
+	}}
+	m.Nodes[1].Roads = []*Road{{To: 3, OneWay: true}}
+	m.Initialize()
+
+	tests := []struct {
+		name   string
+		p      engo.Point
+		point  engo.Point
+		offset float32
+		// from is the node the road starts at, or 0 if the road may go in either direction
+		from uint32
+	}{
+		{"start of the road", engo.Point{X: -5, Y: -5}, engo.Point{X: 0, Y: 0}, 0, 0},
+		{"on the road", engo.Point{X: 30, Y: 0}, engo.Point{X: 30, Y: 0}, 30, 0},
+		{"next to the road", engo.Point{X: 30, Y: 10}, engo.Point{X: 30, Y: 0}, 30, 0},
+		{"one-way road", engo.Point{X: 110, Y: 60}, engo.Point{X: 100, Y: 60}, 60, 2},
+		{"end of the one-way road", engo.Point{X: 100, Y: 150}, engo.Point{X: 100, Y: 100}, 100, 2},
+		{"far off the map", engo.Point{X: -1e5, Y: 10}, engo.Point{X: 0, Y: 0}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := m.SnapToRoad(tt.p)
+			if !pos.OnRoad() {
+				t.Fatal("not on a road")
+			}
+			if tt.from != 0 && pos.From.ID != tt.from {
+				t.Fatalf("on the road from %d, expected from %d", pos.From.ID, tt.from)
+			}
+			if pos.Point.PointDistance(tt.point) > 1e-3 {
+				t.Fatalf("snapped to %v, expected %v", pos.Point, tt.point)
+			}
+
+			// The offset depends on which way the road goes
+			offset := tt.offset
+			if pos.From.ID == 2 && pos.To.ID == 1 {
+				offset = pos.Road.Length - offset
+			}
+			if d := pos.Offset - offset; d > 1e-3 || d < -1e-3 {
+				t.Fatalf("offset %f, expected %f", pos.Offset, offset)
+			}
+			if at := m.PositionAt(pos.RoadSegment, pos.Offset); at.Point.PointDistance(pos.Point) > 1e-3 {
+				t.Fatalf("position at offset %f is %v, expected %v", pos.Offset, at.Point, pos.Point)
+			}
+		})
+	}
+}
+
+func TestSnapToRoadWithoutRoads(t *testing.T) {
+	m := &Map{Nodes: []*RouteNode{{ID: 1, Location: engo.Point{X: 10, Y: 10}}}}
+	m.Initialize()
+	if pos := m.SnapToRoad(engo.Point{X: 1, Y: 2}); pos.OnRoad() || pos.Point != (engo.Point{X: 10, Y: 10}) {
+		t.Fatal(pos)
+	}
+
+	empty := &Map{}
+	empty.Initialize()
+	if pos := empty.SnapToRoad(engo.Point{X: 1, Y: 2}); pos.OnRoad() || pos.Point != (engo.Point{X: 1, Y: 2}) {
+		t.Fatal(pos)
+	}
+}
+
+func TestPositionAtClamps(t *testing.T) {
+	m := RandomMap(2, 2, 100, 100)
+	m.Initialize()
+	seg, _ := m.NearestRoad(engo.Point{X: 100, Y: 150})
+	if pos := m.PositionAt(seg, -10); pos.Offset != 0 || pos.Point != seg.From.Location {
+		t.Fatal(pos)
+	}
+	if pos := m.PositionAt(seg, seg.Road.Length+10); pos.Offset != seg.Road.Length || pos.Point != seg.To.Location {
+		t.Fatal(pos)
+	}
+}
@@ -0,0 +1,144 @@
+package dl
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"engo.io/engo"
+)
+
+// searchRouteLength is the number of roads a search route covers before the next one is planned
+const searchRouteLength = 20
+
+// searchForget is how long (in game time) it takes before a searched road is worth searching again
+const searchForget = 2 * time.Minute
+
+// SearchRoute plans a route from the position which covers the roads within the radius of the center, driving the
+// roads that were searched the longest ago first. A position along a road first finishes driving it, so the route
+// never leaves the roads. visited holds the game time at which roads were last searched, and now is the current game
+// time.
+func (m *Map) SearchRoute(from RoadPosition, center engo.Point, radius, maxSpeed float32, visited map[*Road]time.Duration, now time.Duration) (Route, error) {
+	var area []RoadSegment
+	for _, node := range m.NodesWithin(center, radius) {
+		for _, road := range node.Roads {
+			if to := m.Node(road.To); to != nil && !road.Closed() && to.Location.PointDistance(center) <= radius {
+				area = append(area, RoadSegment{node, to, road})
+			}
+		}
+	}
+	if len(area) == 0 {
+		return Route{}, fmt.Errorf("%w: no roads to search", ErrNoRoute)
+	}
+	sort.Slice(area, func(i, j int) bool {
+		if area[i].From.ID == area[j].From.ID {
+			return area[i].To.ID < area[j].To.ID
+		}
+		return area[i].From.ID < area[j].From.ID
+	})
+
+	// last is when the roads were searched, counting the roads on the route so far as searched now. Roads which were
+	// never searched count as searched long enough ago.
+	last := make(map[*Road]time.Duration, len(area))
+	for _, seg := range area {
+		if t, ok := visited[seg.Road]; ok {
+			last[seg.Road] = t
+		} else {
+			last[seg.Road] = now - searchForget
+		}
+	}
+	recent := func(r *Road) bool {
+		return now-last[r] < searchForget
+	}
+	drive := func(road *Road, to *RouteNode) {
+		if _, ok := last[road]; ok {
+			last[road] = now
+		}
+		if back := reverseRoad(m, to, road); back != nil {
+			if _, ok := last[back]; ok {
+				last[back] = now
+			}
+		}
+	}
+
+	var (
+		curr  *RouteNode
+		first *Road
+	)
+	switch {
+	case !from.OnRoad():
+		curr = m.NearestNode(from.Point)
+	case from.Offset <= 0:
+		curr = from.From
+	case from.Offset >= from.Road.Length:
+		curr = from.To
+	default:
+		curr, first = from.To, from.Road
+		drive(first, curr)
+	}
+
+	cost, h := TravelTimeCost(maxSpeed), TravelTimeHeuristic(maxSpeed)
+	route := Route{Nodes: []*RouteNode{curr}, Roads: []*Road{first}, planned: m.version}
+	for len(route.Roads) <= searchRouteLength {
+		// Drive on along the road searched the longest ago, unless it was searched recently
+		var next *Road
+		for _, road := range curr.Roads {
+			if _, ok := last[road]; !ok || recent(road) {
+				continue
+			}
+			if next == nil || last[road] < last[next] {
+				next = road
+			}
+		}
+		if next != nil {
+			to := m.Node(next.To)
+			route.Nodes = append(route.Nodes, to)
+			route.Roads = append(route.Roads, next)
+			drive(next, to)
+			curr = to
+			continue
+		}
+
+		// Otherwise drive to the closest road which wasn't searched recently
+		var (
+			closest RoadSegment
+			best    float32 = -1
+		)
+		for _, seg := range area {
+			if d := seg.From.Location.PointDistance(curr.Location); !recent(seg.Road) && (best < 0 || d < best) {
+				closest, best = seg, d
+			}
+		}
+		if best < 0 {
+			break
+		}
+		path, err := m.FindRoute(curr, closest.From, cost, h)
+		if err != nil || len(path.Nodes) < 2 {
+			// Don't try to get there again
+			last[closest.Road] = now
+			continue
+		}
+		for i := 1; i < len(path.Nodes); i++ {
+			drive(path.Roads[i], path.Nodes[i])
+		}
+		route.Nodes = append(route.Nodes, path.Nodes[1:]...)
+		route.Roads = append(route.Roads, path.Roads[1:]...)
+		curr = closest.From
+	}
+
+	// If everything was searched recently, the search starts over
+	if len(route.Roads) == 1 && len(visited) > 0 {
+		return m.SearchRoute(from, center, radius, maxSpeed, nil, now)
+	}
+	return route, nil
+}
+
+// reverseRoad returns the road in the other direction of the road to the node, if there is one
+func reverseRoad(m *Map, to *RouteNode, road *Road) *Road {
+	for _, back := range to.Roads {
+		if from := m.Node(back.To); from != nil && from.Road(to.ID) == road {
+			return back
+		}
+	}
+	return nil
+}
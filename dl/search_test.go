@@ -0,0 +1,87 @@
+package dl
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"engo.io/engo"
+)
+
+func checkSearchRoute(t *testing.T, route Route) {
+	t.Helper()
+	if len(route.Nodes) != len(route.Roads) || len(route.Nodes) < 2 {
+		t.Fatalf("%d nodes and %d roads", len(route.Nodes), len(route.Roads))
+	}
+	for i := 1; i < len(route.Nodes); i++ {
+		if route.Nodes[i-1].Road(route.Nodes[i].ID) != route.Roads[i] {
+			t.Fatalf("road %d of the route doesn't lead to node %v", i, route.Nodes[i])
+		}
+	}
+}
+
+func TestSearchRoute(t *testing.T) {
+	m := GenerateCity(DefaultCityOptions(9))
+	m.Initialize()
+	center := m.Nodes[len(m.Nodes)/2].Location
+	from := m.NearestNode(center)
+	now := 10 * time.Minute
+
+	route, err := m.SearchRoute(m.SnapToRoad(from.Location), center, 300, 100, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSearchRoute(t, route)
+	if route.Nodes[0] != from || route.Roads[0] != nil {
+		t.Fatal("the route doesn't start at", from)
+	}
+
+	// Roads searched recently are avoided, but those searched long ago aren't
+	for _, ago := range []time.Duration{0, searchForget / 2, 2 * searchForget} {
+		visited := make(map[*Road]time.Duration)
+		for _, road := range route.Roads[1:] {
+			visited[road] = now - ago
+		}
+		again, err := m.SearchRoute(m.SnapToRoad(from.Location), center, 300, 100, visited, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSearchRoute(t, again)
+		fresh := 0
+		for _, road := range again.Roads[1:] {
+			if _, ok := visited[road]; !ok {
+				fresh++
+			}
+		}
+		if recent := ago < searchForget; recent && fresh == 0 {
+			t.Fatalf("searched %s ago, but the same roads are searched again", ago)
+		} else if !recent && fresh == len(again.Roads)-1 {
+			t.Fatalf("searched %s ago, but the same roads are avoided", ago)
+		}
+	}
+
+	if _, err := m.SearchRoute(m.SnapToRoad(from.Location), engo.Point{X: -1e5, Y: -1e5}, 300, 100, nil, now); !errors.Is(err, ErrNoRoute) {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchRouteFromRoad(t *testing.T) {
+	m := RandomMap(5, 5, 100, 100)
+	m.Initialize()
+
+	// Halfway along a road, the search finishes driving it first
+	pos := m.SnapToRoad(engo.Point{X: 310, Y: 250})
+	route, err := m.SearchRoute(pos, engo.Point{X: 300, Y: 300}, 150, 100, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSearchRoute(t, route)
+	if route.Nodes[0] != pos.To || route.Roads[0] != pos.Road {
+		t.Fatalf("the route starts at %v, expected at the end of the road to %v", route.Nodes[0], pos.To)
+	}
+	for _, road := range route.Roads[1:] {
+		if road == pos.Road {
+			t.Fatal("the road the search started on is searched again")
+		}
+	}
+}
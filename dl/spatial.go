@@ -0,0 +1,353 @@
+package dl
+
+import (
+	"sort"
+
+	"engo.io/engo"
+	"github.com/luxengine/math"
+)
+
+// RoadSegment is a Road together with the nodes on both ends of it
+type RoadSegment struct {
+	From *RouteNode
+	To   *RouteNode
+	Road *Road
+}
+
+// Closest returns the point on the segment closest to p, and how far along the segment (between 0 and 1) it is
+func (s RoadSegment) Closest(p engo.Point) (engo.Point, float32) {
+	// Source for this "distance" method, https://stackoverflow.com/a/6853926/3243814
+	l1, l2 := s.From.Location, s.To.Location
+	A, B := p.X-l1.X, p.Y-l1.Y
+	C, D := l2.X-l1.X, l2.Y-l1.Y
+
+	lenSq := C*C + D*D
+	if lenSq == 0 {
+		return l1, 0
+	}
+
+	param := (A*C + B*D) / lenSq
+	switch {
+	case param < 0:
+		return l1, 0
+	case param > 1:
+		return l2, 1
+	default:
+		return engo.Point{X: l1.X + param*C, Y: l1.Y + param*D}, param
+	}
+}
+
+// Distance returns the shortest distance between p and the segment
+func (s RoadSegment) Distance(p engo.Point) float32 {
+	closest, _ := s.Closest(p)
+	return closest.PointDistance(p)
+}
+
+type gridCell struct {
+	X, Y int32
+}
+
+// spatialIndex is a sparse uniform grid containing all nodes and road segments of a Map. Segments are stored in
+// every cell they pass through.
+type spatialIndex struct {
+	cellSize float32
+	nodes    map[gridCell][]*RouteNode
+	segments map[gridCell][]RoadSegment
+
+	// The range of cells that contain anything, to know when to stop searching
+	min, max gridCell
+	empty    bool
+}
+
+// newSpatialIndex creates an index for the given nodes, using a cell size which puts a few nodes in each cell
+func newSpatialIndex(nodes []*RouteNode) *spatialIndex {
+	var cellSize float32 = 100
+	if len(nodes) > 1 {
+		minP, maxP := nodes[0].Location, nodes[0].Location
+		for _, n := range nodes {
+			minP.X, minP.Y = math.Min(minP.X, n.Location.X), math.Min(minP.Y, n.Location.Y)
+			maxP.X, maxP.Y = math.Max(maxP.X, n.Location.X), math.Max(maxP.Y, n.Location.Y)
+		}
+		if area := (maxP.X - minP.X) * (maxP.Y - minP.Y); area > 0 {
+			cellSize = 2 * math.Sqrt(area/float32(len(nodes)))
+		}
+	}
+
+	return &spatialIndex{
+		cellSize: cellSize,
+		nodes:    make(map[gridCell][]*RouteNode),
+		segments: make(map[gridCell][]RoadSegment),
+		empty:    true,
+	}
+}
+
+func (s *spatialIndex) cellOf(p engo.Point) gridCell {
+	return gridCell{int32(math.Floor(p.X / s.cellSize)), int32(math.Floor(p.Y / s.cellSize))}
+}
+
+func (s *spatialIndex) grow(c gridCell) {
+	if s.empty {
+		s.min, s.max, s.empty = c, c, false
+		return
+	}
+	if c.X < s.min.X {
+		s.min.X = c.X
+	}
+	if c.Y < s.min.Y {
+		s.min.Y = c.Y
+	}
+	if c.X > s.max.X {
+		s.max.X = c.X
+	}
+	if c.Y > s.max.Y {
+		s.max.Y = c.Y
+	}
+}
+
+func (s *spatialIndex) addNode(n *RouteNode) {
+	c := s.cellOf(n.Location)
+	s.nodes[c] = append(s.nodes[c], n)
+	s.grow(c)
+}
+
+func (s *spatialIndex) removeNode(n *RouteNode) {
+	c := s.cellOf(n.Location)
+	list := s.nodes[c]
+	for i, node := range list {
+		if node == n {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(s.nodes, c)
+	} else {
+		s.nodes[c] = list
+	}
+}
+
+// segmentCells calls f for every cell the segment passes through
+func (s *spatialIndex) segmentCells(seg RoadSegment, f func(gridCell)) {
+	// Source for this traversal, "A Fast Voxel Traversal Algorithm for Ray Tracing" by Amanatides & Woo
+	a, b := seg.From.Location, seg.To.Location
+	c, end := s.cellOf(a), s.cellOf(b)
+	dx, dy := b.X-a.X, b.Y-a.Y
+
+	stepX, tMaxX, tDeltaX := s.traversalAxis(a.X, dx, c.X)
+	stepY, tMaxY, tDeltaY := s.traversalAxis(a.Y, dy, c.Y)
+
+	// Limiting the steps prevents rounding errors from going past the end
+	steps := abs32(end.X-c.X) + abs32(end.Y-c.Y)
+	f(c)
+	for i := int32(0); i < steps; i++ {
+		if tMaxX < tMaxY {
+			c.X += stepX
+			tMaxX += tDeltaX
+		} else {
+			c.Y += stepY
+			tMaxY += tDeltaY
+		}
+		f(c)
+	}
+}
+
+// traversalAxis returns the step direction, the fraction of the segment at which the first cell boundary is
+// crossed, and the fraction of the segment it takes to cross a whole cell, for one axis
+func (s *spatialIndex) traversalAxis(start, delta float32, cell int32) (int32, float32, float32) {
+	switch {
+	case delta > 0:
+		boundary := float32(cell+1) * s.cellSize
+		return 1, (boundary - start) / delta, s.cellSize / delta
+	case delta < 0:
+		boundary := float32(cell) * s.cellSize
+		return -1, (boundary - start) / delta, -s.cellSize / delta
+	default:
+		return 0, math.MaxFloat32, math.MaxFloat32
+	}
+}
+
+func abs32(i int32) int32 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (s *spatialIndex) addSegment(seg RoadSegment) {
+	s.segmentCells(seg, func(c gridCell) {
+		s.segments[c] = append(s.segments[c], seg)
+		s.grow(c)
+	})
+}
+
+func (s *spatialIndex) removeSegment(seg RoadSegment) {
+	s.segmentCells(seg, func(c gridCell) {
+		list := s.segments[c]
+		for i, other := range list {
+			if other.Road == seg.Road {
+				list = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(list) == 0 {
+			delete(s.segments, c)
+		} else {
+			s.segments[c] = list
+		}
+	})
+}
+
+// ring calls f for every cell at exactly the given Chebyshev distance from the center cell, skipping cells outside
+// of the range that contains anything
+func (s *spatialIndex) ring(center gridCell, r int32, f func(gridCell)) {
+	inX := func(x int32) bool { return x >= s.min.X && x <= s.max.X }
+	inY := func(y int32) bool { return y >= s.min.Y && y <= s.max.Y }
+
+	if r == 0 {
+		if inX(center.X) && inY(center.Y) {
+			f(center)
+		}
+		return
+	}
+
+	fromX, toX := max32(center.X-r, s.min.X), min32(center.X+r, s.max.X)
+	for _, y := range []int32{center.Y - r, center.Y + r} {
+		if !inY(y) {
+			continue
+		}
+		for x := fromX; x <= toX; x++ {
+			f(gridCell{x, y})
+		}
+	}
+
+	fromY, toY := max32(center.Y-r+1, s.min.Y), min32(center.Y+r-1, s.max.Y)
+	for _, x := range []int32{center.X - r, center.X + r} {
+		if !inX(x) {
+			continue
+		}
+		for y := fromY; y <= toY; y++ {
+			f(gridCell{x, y})
+		}
+	}
+}
+
+// ringRange returns the first and last ring around the center containing cells within the range that contains
+// anything
+func (s *spatialIndex) ringRange(center gridCell) (int32, int32) {
+	first := max32(max32(s.min.X-center.X, center.X-s.max.X), max32(s.min.Y-center.Y, center.Y-s.max.Y))
+	last := max32(max32(center.X-s.min.X, s.max.X-center.X), max32(center.Y-s.min.Y, s.max.Y-center.Y))
+	return max32(first, 0), last
+}
+
+// search visits the rings around p one by one, until done returns true for the minimum distance anything in the
+// next ring can have
+func (s *spatialIndex) search(p engo.Point, visit func(gridCell), done func(minDistance float32) bool) {
+	if s.empty {
+		return
+	}
+	center := s.cellOf(p)
+	first, last := s.ringRange(center)
+	for r := first; r <= last; r++ {
+		s.ring(center, r, visit)
+		// Anything in ring r+1 is at least r cells away from p, since p can be anywhere within its own cell
+		if done(float32(r) * s.cellSize) {
+			return
+		}
+	}
+}
+
+func (s *spatialIndex) nearestNodes(p engo.Point, k int) []*RouteNode {
+	if k <= 0 {
+		return nil
+	}
+
+	type candidate struct {
+		node     *RouteNode
+		distance float32
+	}
+	var best []candidate
+
+	s.search(p, func(c gridCell) {
+		for _, n := range s.nodes[c] {
+			d := n.Location.PointDistance(p)
+			if len(best) == k && d >= best[k-1].distance {
+				continue
+			}
+			i := sort.Search(len(best), func(i int) bool { return best[i].distance > d })
+			best = append(best, candidate{})
+			copy(best[i+1:], best[i:])
+			best[i] = candidate{n, d}
+			if len(best) > k {
+				best = best[:k]
+			}
+		}
+	}, func(minDistance float32) bool {
+		return len(best) == k && best[k-1].distance <= minDistance
+	})
+
+	nodes := make([]*RouteNode, len(best))
+	for i, c := range best {
+		nodes[i] = c.node
+	}
+	return nodes
+}
+
+func (s *spatialIndex) nodesWithin(p engo.Point, radius float32) []*RouteNode {
+	var nodes []*RouteNode
+	if s.empty {
+		return nodes
+	}
+
+	// Only the cells within the range that contains anything are worth looking at. Clamping before converting to cells
+	// keeps a huge radius from overflowing.
+	clamp := func(v float32, min, max int32) int32 {
+		return int32(math.Min(math.Max(math.Floor(v/s.cellSize), float32(min)), float32(max)))
+	}
+	from := gridCell{clamp(p.X-radius, s.min.X, s.max.X), clamp(p.Y-radius, s.min.Y, s.max.Y)}
+	to := gridCell{clamp(p.X+radius, s.min.X, s.max.X), clamp(p.Y+radius, s.min.Y, s.max.Y)}
+	radiusSq := radius * radius
+	for x := from.X; x <= to.X; x++ {
+		for y := from.Y; y <= to.Y; y++ {
+			for _, n := range s.nodes[gridCell{x, y}] {
+				if n.Location.PointDistanceSquared(p) <= radiusSq {
+					nodes = append(nodes, n)
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+func (s *spatialIndex) nearestSegment(p engo.Point) (RoadSegment, float32, bool) {
+	var (
+		best     RoadSegment
+		distance float32 = math.MaxFloat32
+		found    bool
+	)
+
+	s.search(p, func(c gridCell) {
+		for _, seg := range s.segments[c] {
+			if d := seg.Distance(p); d < distance {
+				best, distance, found = seg, d, true
+			}
+		}
+	}, func(minDistance float32) bool {
+		return found && distance <= minDistance
+	})
+
+	return best, distance, found
+}
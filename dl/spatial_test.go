@@ -0,0 +1,123 @@
+package dl
+
+import (
+	"math/rand"
+	"testing"
+
+	"engo.io/engo"
+)
+
+// scatteredMap returns a map of n nodes at random locations, each connected to one of the nodes before it
+func scatteredMap(n int, size float32, rng *rand.Rand) *Map {
+	m := &Map{}
+	for i := 0; i < n; i++ {
+		m.Nodes = append(m.Nodes, &RouteNode{ID: uint32(i + 1), Location: engo.Point{X: rng.Float32() * size, Y: rng.Float32() * size}})
+	}
+	for i := 1; i < n; i++ {
+		m.Nodes[i].ConnectedTo = []uint32{uint32(rng.Intn(i) + 1)}
+	}
+	m.Initialize()
+	return m
+}
+
+// TestSpatialIndex compares the index with looking at every node and road
+func TestSpatialIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	m := scatteredMap(3000, 20000, rng)
+	for k := 0; k < 300; k++ {
+		// Some of the points are outside of the map
+		p := engo.Point{X: rng.Float32()*24000 - 2000, Y: rng.Float32()*24000 - 2000}
+
+		nearest := float32(-1)
+		within := 0
+		for _, n := range m.Nodes {
+			d := n.Location.PointDistance(p)
+			if nearest < 0 || d < nearest {
+				nearest = d
+			}
+			if d <= 500 {
+				within++
+			}
+		}
+		if d := m.NearestNode(p).Location.PointDistance(p); d != nearest {
+			t.Fatalf("nearest node to %v is %f away, expected %f", p, d, nearest)
+		}
+		if nodes := m.NearestNodes(p, 5); len(nodes) != 5 || nodes[0].Location.PointDistance(p) != nearest {
+			t.Fatal("nearest 5 nodes to", p, nodes)
+		}
+		if got := len(m.NodesWithin(p, 500)); got != within {
+			t.Fatalf("%d nodes within 500 of %v, expected %d", got, p, within)
+		}
+
+		nearest = -1
+		for _, n := range m.Nodes {
+			for _, road := range n.Roads {
+				if d := (RoadSegment{n, m.Node(road.To), road}).Distance(p); nearest < 0 || d < nearest {
+					nearest = d
+				}
+			}
+		}
+		if seg, ok := m.NearestRoad(p); !ok || seg.Distance(p) != nearest {
+			t.Fatalf("nearest road to %v is %f away, expected %f", p, seg.Distance(p), nearest)
+		}
+	}
+}
+
+func TestSpatialIndexFar(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+	if n := m.NearestNode(engo.Point{X: 1e5, Y: -1e5}); n.Location != (engo.Point{X: 1000, Y: 100}) {
+		t.Fatal(n)
+	}
+	if n := m.NearestNode(engo.Point{X: -1e5, Y: 450}); n.Location.Y != 400 && n.Location.Y != 500 {
+		t.Fatal(n)
+	}
+
+	// A huge radius only visits the cells of the map
+	for _, radius := range []float32{2000, 1e6, 1e12} {
+		if nodes := m.NodesWithin(engo.Point{X: 500, Y: 500}, radius); len(nodes) != len(m.Nodes) {
+			t.Fatalf("%d nodes within %g, expected all %d", len(nodes), radius, len(m.Nodes))
+		}
+	}
+	if nodes := m.NodesWithin(engo.Point{X: -1e6, Y: -1e6}, 1000); len(nodes) != 0 {
+		t.Fatal(nodes)
+	}
+}
+
+func BenchmarkNearestNode(b *testing.B) {
+	m := RandomMap(250, 200, 100, 100)
+	m.Initialize()
+	rng := rand.New(rand.NewSource(3))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.NearestNode(engo.Point{X: rng.Float32() * 25000, Y: rng.Float32() * 20000})
+	}
+}
+
+func BenchmarkNearestSegment(b *testing.B) {
+	m := RandomMap(250, 200, 100, 100)
+	m.Initialize()
+	rng := rand.New(rand.NewSource(3))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.NearestRoad(engo.Point{X: rng.Float32() * 25000, Y: rng.Float32() * 20000})
+	}
+}
+
+func BenchmarkNearestNodeScattered(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	m := scatteredMap(50000, 20000, rng)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.NearestNode(engo.Point{X: rng.Float32() * 20000, Y: rng.Float32() * 20000})
+	}
+}
+
+func BenchmarkNearestSegmentScattered(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	m := scatteredMap(50000, 20000, rng)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.NearestRoad(engo.Point{X: rng.Float32() * 20000, Y: rng.Float32() * 20000})
+	}
+}
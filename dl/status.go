@@ -0,0 +1,129 @@
+package dl
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"time"
+
+	"engo.io/engo"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// UnitStatus is what a unit is doing, as far as the dispatcher is concerned
+type UnitStatus uint8
+
+const (
+	StatusAvailable UnitStatus = iota
+	StatusEnRoute
+	StatusOnScene
+	StatusTransporting
+	StatusOutOfService
+)
+
+var unitStatusNames = map[UnitStatus]string{
+	StatusAvailable:    "available",
+	StatusEnRoute:      "en route",
+	StatusOnScene:      "on scene",
+	StatusTransporting: "transporting",
+	StatusOutOfService: "out of service",
+}
+
+// statusTransitions are the statuses a unit can change to, by its current status. Units which are transporting
+// suspects have to drop them off before going out of service or to a scene, and units which are out of service have
+// to become available first.
+var statusTransitions = map[UnitStatus][]UnitStatus{
+	StatusAvailable:    {StatusEnRoute, StatusOnScene, StatusTransporting, StatusOutOfService},
+	StatusEnRoute:      {StatusAvailable, StatusOnScene, StatusTransporting, StatusOutOfService},
+	StatusOnScene:      {StatusAvailable, StatusEnRoute, StatusTransporting, StatusOutOfService},
+	StatusTransporting: {StatusAvailable, StatusEnRoute},
+	StatusOutOfService: {StatusAvailable},
+}
+
+func (s UnitStatus) String() string {
+	if name, ok := unitStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("UnitStatus(%d)", s)
+}
+
+// Color is the color in which units with the status are drawn
+func (s UnitStatus) Color() color.Color {
+	return ui.StatusColors[int(s)%len(ui.StatusColors)]
+}
+
+// CanChangeTo indicates whether or not a unit with the status can change to the other status
+func (s UnitStatus) CanChangeTo(to UnitStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange is a change of the status of a unit
+type StatusChange struct {
+	From, To UnitStatus
+	// At is the game time of the change
+	At time.Duration
+}
+
+// UnitStatusChangedMessage is sent whenever the status of a unit changes
+type UnitStatusChangedMessage struct {
+	Unit   *PoliceComponent
+	Change StatusChange
+}
+
+func (UnitStatusChangedMessage) Type() string { return "UnitStatusChangedMessage" }
+
+// SetStatus changes the status of the unit, if it can change to it from its current status. Going out of service
+// cancels all orders of the unit.
+func (p *PoliceComponent) SetStatus(status UnitStatus) error {
+	if p.Status == status {
+		return nil
+	}
+	if !p.Status.CanChangeTo(status) {
+		return fmt.Errorf("%w: %s can't go from %s to %s", ErrInvalidTransition, p.Unit.Name, p.Status, status)
+	}
+	if status == StatusOutOfService && p.Arrested > 0 {
+		return fmt.Errorf("%w: %s has to drop off its suspects first", ErrInvalidTransition, p.Unit.Name)
+	}
+
+	change := StatusChange{From: p.Status, To: status, At: gameTime}
+	p.Status = status
+	p.StatusHistory = append(p.StatusHistory, change)
+	if status == StatusOutOfService {
+		p.CancelOrders()
+	}
+	engo.Mailbox.Dispatch(UnitStatusChangedMessage{p, change})
+	return nil
+}
+
+// StatusSince returns the game time at which the unit got its current status, which is 0 if it never changed
+func (p *PoliceComponent) StatusSince() time.Duration {
+	if len(p.StatusHistory) == 0 {
+		return 0
+	}
+	return p.StatusHistory[len(p.StatusHistory)-1].At
+}
+
+// commandStatus returns the status that goes with what the unit is doing
+func (p *PoliceComponent) commandStatus() UnitStatus {
+	switch p.Current.Command {
+	case CommandMove, CommandPursue:
+		return StatusEnRoute
+	case CommandLookout, CommandSearchArea, CommandTrafficControl:
+		return StatusOnScene
+	case CommandTransport:
+		return StatusTransporting
+	case CommandHold:
+		if len(p.suspended)+len(p.Orders) > 0 {
+			// It's about to do whatever is next
+			return p.Status
+		}
+	}
+	return StatusAvailable
+}
@@ -0,0 +1,64 @@
+package dl
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"engo.io/engo"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	defer func(old time.Duration) { gameTime = old }(gameTime)
+	gameTime = 0
+
+	p := &PoliceComponent{Unit: UnitType{Name: "Car"}}
+	if p.StatusSince() != 0 {
+		t.Fatal(p.StatusSince())
+	}
+	for _, tt := range []struct {
+		at     time.Duration
+		status UnitStatus
+		err    error
+	}{
+		{time.Second, StatusEnRoute, nil},
+		{2 * time.Second, StatusTransporting, nil},
+		{3 * time.Second, StatusOutOfService, ErrInvalidTransition},
+		{4 * time.Second, StatusAvailable, nil},
+	} {
+		gameTime = tt.at
+		if err := p.SetStatus(tt.status); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: got %v, expected %v", tt.status, err, tt.err)
+		}
+	}
+	if len(p.StatusHistory) != 3 {
+		t.Fatal(p.StatusHistory)
+	}
+	if p.StatusSince() != 4*time.Second || p.StatusHistory[1].At != 2*time.Second {
+		t.Fatal("the changes weren't stamped with the game time:", p.StatusHistory)
+	}
+
+	// Units can't go out of service with suspects on board
+	p.Arrested = 1
+	if err := p.SetStatus(StatusOutOfService); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatal(err)
+	}
+	p.Arrested = 0
+
+	// Going out of service cancels all orders, and the unit has to become available first
+	p.QueueOrder(NewOrder(CommandMove, engo.Point{}, nil))
+	p.Current = p.nextOrder()
+	p.QueueOrder(NewOrder(CommandLookout, engo.Point{}, nil))
+	if p.commandStatus() != StatusEnRoute {
+		t.Fatal(p.commandStatus())
+	}
+	if err := p.SetStatus(StatusOutOfService); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Orders) != 0 || p.Current.Command != CommandHold {
+		t.Fatal("the orders weren't cancelled")
+	}
+	if err := p.SetStatus(StatusEnRoute); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+package dl
+
+// AcquireNode returns a node at the given position, which can be used as target for a command. If there is no node
+// at that position, a temporary one is inserted into the road. Every call to AcquireNode should be followed by a
+// call to ReleaseNode once the node is no longer needed. Acquiring the same position more than once returns the same
+// node, even if the road was split there in the meantime.
+func (m *Map) AcquireNode(pos RoadPosition) *RouteNode {
+	if pos.OnRoad() && (m.Node(pos.From.ID) != pos.From || pos.From.Road(pos.To.ID) != pos.Road) {
+		// The road was split or joined since the position was found
+		pos = m.SnapToRoad(pos.Point)
+	}
+
+	var n *RouteNode
+	switch {
+	case !pos.OnRoad():
+		n = m.NearestNode(pos.Point)
+	case pos.Offset <= 0:
+		n = pos.From
+	case pos.Offset >= pos.Road.Length:
+		n = pos.To
+	default:
+		// The road may already have been split at this position
+		if n = m.NearestNode(pos.Point); n == nil || !n.Temporary || n.Location != pos.Point {
+			n = m.splitRoad(pos)
+		}
+	}
+
+	if n != nil && n.Temporary {
+		n.TemporaryUsers++
+	}
+	return n
+}
+
+// ReleaseNode indicates the node is no longer needed by whoever acquired it. Temporary nodes are removed once
+// nobody needs them anymore, and the roads they split are restored.
+func (m *Map) ReleaseNode(n *RouteNode) {
+	if n == nil || !n.Temporary {
+		return
+	}
+
+	if n.TemporaryUsers > 0 {
+		n.TemporaryUsers--
+	}
+	if n.TemporaryUsers == 0 {
+		m.removeTemporary(n)
+	}
+}
+
+// splitRoad inserts a new temporary node at the position, replacing the road (and the road going in the opposite
+// direction, if any) by roads to and from that node
+func (m *Map) splitRoad(pos RoadPosition) *RouteNode {
+	temp := new(RouteNode)
+	temp.ID = NewMapID()
+	temp.Location = pos.Point
+	temp.Temporary = true
+	m.AddNode(temp)
+
+	fraction := pos.Fraction()
+	m.splitSingleRoad(pos.From, pos.To, pos.Road, temp, fraction)
+	if reverse := pos.To.Road(pos.From.ID); reverse != nil {
+		m.splitSingleRoad(pos.To, pos.From, reverse, temp, 1-fraction)
+	}
+
+	return temp
+}
+
+func (m *Map) splitSingleRoad(from, to *RouteNode, road *Road, temp *RouteNode, fraction float32) {
+	m.removeRoad(from, road)
+
+	first, second := *road, *road
+	if road.whole == 0 {
+		first.whole, second.whole = road.Length, road.Length
+	}
+	first.To, first.Length = temp.ID, road.Length*fraction
+	second.To, second.Length = to.ID, road.Length*(1-fraction)
+	first.Numbers, second.Numbers = road.Numbers.split(fraction)
+	first.inheritRestrictions(road)
+	second.inheritRestrictions(road)
+	m.addRoad(from, temp, &first)
+	m.addRoad(temp, to, &second)
+}
+
+// removeTemporary removes the node, joining the roads leading to and from it
+func (m *Map) removeTemporary(n *RouteNode) {
+	incoming := m.incomingRoads(n)
+	for _, in := range incoming {
+		for _, out := range n.Roads {
+			if out.To == in.From.ID || in.From.Road(out.To) != nil {
+				continue
+			}
+
+			to := m.Node(out.To)
+			if to == nil {
+				continue
+			}
+			joined := *in.Road
+			joined.To = out.To
+			joined.Length += out.Length
+			if !in.From.Temporary && !to.Temporary {
+				joined.Length, joined.whole = joined.whole, 0
+			}
+			joined.Numbers.Last = out.Numbers.Last
+			joined.inheritRestrictions(in.Road, out)
+			m.addRoad(in.From, to, &joined)
+		}
+	}
+
+	for _, in := range incoming {
+		m.removeRoad(in.From, in.Road)
+	}
+	for len(n.Roads) > 0 {
+		m.removeRoad(n, n.Roads[0])
+	}
+	m.removeNode(n)
+}
+
+// incomingRoads returns all roads leading to the node
+func (m *Map) incomingRoads(n *RouteNode) []RoadSegment {
+	var segments []RoadSegment
+	for _, node := range m.Nodes {
+		if road := node.Road(n.ID); road != nil {
+			segments = append(segments, RoadSegment{node, n, road})
+		}
+	}
+	return segments
+}
+
+func (m *Map) removeRoad(from *RouteNode, road *Road) {
+	m.version++
+	m.roadChanged(from, m.Node(road.To), road)
+	for i, r := range from.Roads {
+		if r == road {
+			from.Roads = append(from.Roads[:i], from.Roads[i+1:]...)
+			break
+		}
+	}
+	for i, id := range from.ConnectedTo {
+		if id == road.To {
+			from.ConnectedTo = append(from.ConnectedTo[:i], from.ConnectedTo[i+1:]...)
+			break
+		}
+	}
+
+	if m.index != nil {
+		if to := m.Node(road.To); to != nil {
+			m.index.removeSegment(RoadSegment{from, to, road})
+		}
+	}
+}
+
+// removeNode removes the node itself from the map; it assumes there are no roads leading to or from it
+func (m *Map) removeNode(n *RouteNode) {
+	m.version++
+	for i, node := range m.Nodes {
+		if node == n {
+			m.Nodes = append(m.Nodes[:i], m.Nodes[i+1:]...)
+			break
+		}
+	}
+	delete(m.nodesMap, n.ID)
+	if m.index != nil {
+		m.index.removeNode(n)
+	}
+}
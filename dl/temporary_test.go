@@ -0,0 +1,138 @@
+package dl
+
+import (
+	"math/rand"
+	"testing"
+
+	"engo.io/engo"
+)
+
+func roadLengths(m *Map) map[[2]uint32]float32 {
+	lengths := make(map[[2]uint32]float32)
+	for _, n := range m.Nodes {
+		for _, r := range n.Roads {
+			lengths[[2]uint32{n.ID, r.To}] = r.Length
+		}
+	}
+	return lengths
+}
+
+func checkRestored(t *testing.T, m *Map, nodes int, lengths map[[2]uint32]float32) {
+	t.Helper()
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Nodes) != nodes {
+		t.Fatalf("%d nodes, expected %d", len(m.Nodes), nodes)
+	}
+	after := roadLengths(m)
+	if len(after) != len(lengths) {
+		t.Fatalf("%d roads, expected %d", len(after), len(lengths))
+	}
+	for key, l := range lengths {
+		if d := after[key] - l; d > 0.01 || d < -0.01 {
+			t.Fatalf("road %d-%d is %f long, expected %f", key[0], key[1], after[key], l)
+		}
+	}
+}
+
+func TestAcquireRelease(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	lengths := roadLengths(m)
+
+	pos := m.SnapToRoad(engo.Point{130, 160})
+	n := m.AcquireNode(pos)
+	if !n.Temporary || n.Location != pos.Point || n.TemporaryUsers != 1 {
+		t.Fatalf("%+v", n)
+	}
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Nodes) != 10 {
+		t.Fatal(len(m.Nodes))
+	}
+
+	m.ReleaseNode(n)
+	checkRestored(t, m, 9, lengths)
+}
+
+func TestAcquireSamePosition(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	lengths := roadLengths(m)
+
+	// The second position is stale: its road was split by the first acquire
+	pos := m.SnapToRoad(engo.Point{130, 160})
+	first := m.AcquireNode(pos)
+	second := m.AcquireNode(pos)
+	third := m.AcquireNode(m.SnapToRoad(pos.Point))
+	if first != second || first != third {
+		t.Fatal("acquired different nodes at", pos.Point)
+	}
+	if first.TemporaryUsers != 3 || len(m.Nodes) != 10 {
+		t.Fatal(first.TemporaryUsers, len(m.Nodes))
+	}
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.ReleaseNode(first)
+	m.ReleaseNode(second)
+	if len(m.Nodes) != 10 {
+		t.Fatal("released while in use")
+	}
+	m.ReleaseNode(third)
+	checkRestored(t, m, 9, lengths)
+}
+
+func TestAcquireStalePosition(t *testing.T) {
+	m := RandomMap(3, 3, 100, 100)
+	m.Initialize()
+	lengths := roadLengths(m)
+
+	// Both positions are on the same road, so the second one is split off the first split
+	a, b := m.SnapToRoad(engo.Point{130, 130}), m.SnapToRoad(engo.Point{130, 170})
+	na, nb := m.AcquireNode(a), m.AcquireNode(b)
+	if na == nb || nb.Location != b.Point {
+		t.Fatal(na, nb)
+	}
+	if err := m.Check(); err != nil {
+		t.Fatal(err)
+	}
+	m.ReleaseNode(na)
+	m.ReleaseNode(nb)
+	checkRestored(t, m, 9, lengths)
+}
+
+func TestAcquireReleaseRandom(t *testing.T) {
+	m := RandomMap(8, 8, 100, 100)
+	m.Nodes[5].Roads[0].OneWay = true
+	m.Initialize()
+	lengths := roadLengths(m)
+
+	rng := rand.New(rand.NewSource(5))
+	var held []*RouteNode
+	for k := 0; k < 5000; k++ {
+		if rng.Intn(2) == 0 || len(held) == 0 {
+			p := engo.Point{rng.Float32() * 900, rng.Float32() * 900}
+			if rng.Intn(4) == 0 && len(held) > 0 {
+				p = held[rng.Intn(len(held))].Location
+			}
+			held = append(held, m.AcquireNode(m.SnapToRoad(p)))
+		} else {
+			i := rng.Intn(len(held))
+			m.ReleaseNode(held[i])
+			held = append(held[:i], held[i+1:]...)
+		}
+		if k%100 == 0 {
+			if err := m.Check(); err != nil {
+				t.Fatal(k, err)
+			}
+		}
+	}
+	for _, n := range held {
+		m.ReleaseNode(n)
+	}
+	checkRestored(t, m, 64, lengths)
+}
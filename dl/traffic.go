@@ -0,0 +1,185 @@
+package dl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"engo.io/ecs"
+	"github.com/luxengine/math"
+	"gopkg.in/yaml.v2"
+)
+
+// trafficSteps is the number of times per hour the traffic is updated
+const trafficSteps = 4
+
+// TrafficProfile is the factor by which traffic slows down driving, for every hour of the day. 1 means the traffic
+// flows freely.
+type TrafficProfile [24]float32
+
+// At returns the factor at the given time of day, in hours since midnight
+func (p TrafficProfile) At(hour float32) float32 {
+	hour = math.Mod(hour, 24)
+	if hour < 0 {
+		hour += 24
+	}
+	i := int(hour)
+	fraction := hour - float32(i)
+	return p[i]*(1-fraction) + p[(i+1)%24]*fraction
+}
+
+// TrafficProfiles holds the profile of every class of road. Roads of classes without a profile flow freely.
+type TrafficProfiles map[RoadClass]TrafficProfile
+
+// DefaultTrafficProfiles has rush hours in the morning and in the afternoon, which are the worst on the main roads
+var DefaultTrafficProfiles = TrafficProfiles{
+	RoadHighway: {
+		1, 1, 1, 1, 1, 0.95, 0.8, 0.5, 0.45, 0.7, 0.85, 0.85,
+		0.8, 0.85, 0.85, 0.7, 0.5, 0.45, 0.6, 0.8, 0.9, 0.95, 1, 1,
+	},
+	RoadArterial: {
+		1, 1, 1, 1, 1, 0.95, 0.8, 0.55, 0.5, 0.7, 0.8, 0.8,
+		0.75, 0.8, 0.8, 0.7, 0.55, 0.5, 0.65, 0.8, 0.9, 0.95, 1, 1,
+	},
+	RoadResidential: {
+		1, 1, 1, 1, 1, 1, 0.95, 0.85, 0.85, 0.95, 0.95, 0.95,
+		0.9, 0.95, 0.95, 0.9, 0.85, 0.85, 0.9, 0.95, 1, 1, 1, 1,
+	},
+}
+
+// Validate checks whether every factor slows down driving, without stopping it: each has to be above 0 and at most 1
+func (p TrafficProfiles) Validate() error {
+	var problems []string
+	for class, profile := range p {
+		for hour, factor := range profile {
+			if factor <= 0 || factor > 1 {
+				problems = append(problems, fmt.Sprintf("%s at %02d:00: factor %g is not above 0 and at most 1",
+					class, hour, factor))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%s", strings.Join(problems, "\n"))
+}
+
+// LoadTrafficProfiles reads the traffic profiles from a YAML file, which lists 24 factors for every class of road. It
+// fails on any field a profile doesn't have.
+func LoadTrafficProfiles(filename string) (TrafficProfiles, error) {
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+	default:
+		return nil, fmt.Errorf("unsupported traffic file %s: use .yaml", filename)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var traffic struct {
+		Profiles TrafficProfiles `yaml:"profiles"`
+	}
+
+	if err := yaml.UnmarshalStrict(b, &traffic); err != nil {
+		return nil, fmt.Errorf("invalid traffic %s: %v", filename, err)
+	}
+	if err := traffic.Profiles.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid traffic %s:\n%v", filename, err)
+	}
+
+	return traffic.Profiles, nil
+}
+
+// Traffic returns the factor by which traffic slows down driving on the road, 1 meaning it flows freely
+func (r *Road) Traffic() float32 {
+	if r.traffic <= 0 {
+		return 1
+	}
+	return r.traffic
+}
+
+// TrafficSystem lets time pass, and updates the traffic on every road of the map accordingly. Traffic depends on the
+// time of day, and backs up in front of roads that are closed or slowed down.
+type TrafficSystem struct {
+	Map      *Map
+	Profiles TrafficProfiles
+	// Hour is the time of day, in hours since midnight
+	Hour float32
+	// HoursPerSecond is how fast time passes in the game
+	HoursPerSecond float32
+
+	step    int
+	version uint64
+}
+
+func (t *TrafficSystem) New(w *ecs.World) {
+	if t.Profiles == nil {
+		t.Profiles = DefaultTrafficProfiles
+	}
+	if t.HoursPerSecond == 0 {
+		t.HoursPerSecond = 1.0 / 60
+	}
+	t.update()
+}
+
+func (t *TrafficSystem) Remove(b ecs.BasicEntity) {}
+
+func (t *TrafficSystem) Update(dt float32) {
+	t.Hour = math.Mod(t.Hour+dt*t.HoursPerSecond, 24)
+	if int(t.Hour*trafficSteps) != t.step || t.Map.Version() != t.version {
+		t.update()
+	}
+}
+
+func (t *TrafficSystem) update() {
+	t.step = int(t.Hour * trafficSteps)
+
+	// Half of the slowdown of the roads leaving a node backs up into the roads leading to it
+	backup := make(map[uint32]float32, len(t.Map.Nodes))
+	for _, node := range t.Map.Nodes {
+		backup[node.ID] = 1
+		for _, road := range node.Roads {
+			backup[node.ID] = math.Min(backup[node.ID], (1+road.SpeedFactor())/2)
+		}
+	}
+
+	traffic := make(map[*Road]float32)
+	for _, node := range t.Map.Nodes {
+		for _, road := range node.Roads {
+			factor, ok := backup[road.To]
+			if !ok {
+				continue
+			}
+			if profile, ok := t.Profiles[road.Class]; ok {
+				factor *= profile.At(t.Hour)
+			}
+			traffic[road] = factor
+		}
+	}
+	t.Map.setTraffic(traffic)
+	t.version = t.Map.Version()
+}
+
+// setTraffic changes the traffic on the roads, which only counts as a change in traffic if it's noticeable
+func (m *Map) setTraffic(traffic map[*Road]float32) {
+	var changed []*Road
+	for road, factor := range traffic {
+		if math.Abs(road.Traffic()-factor) > 0.01 {
+			road.traffic = factor
+			changed = append(changed, road)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	m.trafficVersion++
+	for _, road := range changed {
+		road.trafficChanged = m.trafficVersion
+	}
+}
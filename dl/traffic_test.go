@@ -0,0 +1,129 @@
+package dl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"engo.io/engo"
+)
+
+func TestTrafficKeepsVersion(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+	ts := &TrafficSystem{Map: m, Hour: 3}
+	ts.New(nil)
+
+	cost := TravelTimeCost(200)
+	route, err := m.FindRoute(m.Nodes[0], m.Nodes[len(m.Nodes)-1], cost, TravelTimeHeuristic(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, traffic := m.Version(), m.TrafficVersion()
+
+	// Rush hour
+	ts.Update(5 * 60)
+	if m.TrafficVersion() == traffic {
+		t.Fatal("the traffic didn't change")
+	}
+	if m.Version() != version || m.NeedsReplanning(&route) {
+		t.Fatal("the traffic changed the map")
+	}
+
+	// Closing a road is a change of the map, and the traffic backs up in front of it
+	n := m.NearestNode(engo.Point{500, 500})
+	in := m.incomingRoads(n)[0].Road
+	before := in.Traffic()
+	m.CloseRoads(n.Roads...)
+	ts.Update(0)
+	if m.Version() == version || in.Traffic() > before*0.51 {
+		t.Fatal(in.Traffic(), before)
+	}
+}
+
+func TestPlannerFollowsTraffic(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+
+	cost, h := TravelTimeCost(200), TravelTimeHeuristic(200)
+	p := NewPlanner(m, cost, h)
+	start, goal := m.NearestNode(engo.Point{100, 100}), m.NearestNode(engo.Point{1000, 600})
+	for k := 0; k < 3; k++ {
+		got, err := p.Route(start, goal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := m.FindRoute(start, goal, cost, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := got.Cost(cost) - want.Cost(cost); d > 0.01 || d < -0.01 {
+			t.Fatalf("the planner's route costs %f, but the cheapest costs %f", got.Cost(cost), want.Cost(cost))
+		}
+
+		// A traffic jam on the route makes another one cheaper
+		jam := make(map[*Road]float32)
+		for _, road := range got.Roads[1:] {
+			jam[road] = 0.1
+		}
+		m.setTraffic(jam)
+	}
+}
+
+func TestLoadTrafficProfiles(t *testing.T) {
+	profiles, err := LoadTrafficProfiles("../assets/traffic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != len(DefaultTrafficProfiles) {
+		t.Fatalf("loaded %d profiles, expected %d", len(profiles), len(DefaultTrafficProfiles))
+	}
+	for class, profile := range DefaultTrafficProfiles {
+		if profiles[class] != profile {
+			t.Fatalf("the %s profile differs from the default", class)
+		}
+	}
+
+	hours := func(factor string) string {
+		return "[" + strings.TrimSuffix(strings.Repeat("1, ", 23), ", ") + ", " + factor + "]"
+	}
+	tests := []struct {
+		name, file, content string
+		// problem is part of the error, or empty if the file is valid
+		problem string
+	}{
+		{"valid", "traffic.yml", "profiles:\n  highway: " + hours("0.5") + "\n", ""},
+		{"unknown extension", "traffic.json", `{"profiles": {}}`, "unsupported"},
+		{"unknown field", "traffic.yaml", "profiles: {}\nrush: 8\n", "rush"},
+		{"unknown class", "traffic.yaml", "profiles:\n  motorway: " + hours("1") + "\n", "motorway"},
+		{"factor of 0", "traffic.yaml", "profiles:\n  highway: " + hours("0") + "\n", "highway at 23:00"},
+		{"factor above 1", "traffic.yaml", "profiles:\n  arterial: " + hours("1.5") + "\n", "arterial at 23:00"},
+		{"missing hours", "traffic.yaml", "profiles:\n  residential: [1, 1]\n", "24 elements"},
+	}
+
+	dir, err := ioutil.TempDir("", "traffic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.file)
+			if err := ioutil.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadTrafficProfiles(filename)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatal(err)
+			case tt.problem != "" && err == nil:
+				t.Fatalf("loaded the profiles, expected an error about %q", tt.problem)
+			case err != nil && !strings.Contains(err.Error(), tt.problem):
+				t.Fatalf("error %q doesn't mention %q", err, tt.problem)
+			}
+		})
+	}
+}
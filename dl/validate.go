@@ -0,0 +1,341 @@
+package dl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MapError is a problem with a map, which may be about a specific node and line of the map file
+type MapError struct {
+	Line    int
+	Node    uint32
+	Message string
+}
+
+func (e MapError) Error() string {
+	buf := &bytes.Buffer{}
+	if e.Line > 0 {
+		fmt.Fprintf(buf, "line %d: ", e.Line)
+	}
+	if e.Node > 0 {
+		fmt.Fprintf(buf, "node %d: ", e.Node)
+	}
+	buf.WriteString(e.Message)
+	return buf.String()
+}
+
+// MapErrors contains every problem found in a map
+type MapErrors []MapError
+
+func (e MapErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *MapErrors) add(node uint32, format string, args ...interface{}) {
+	*e = append(*e, MapError{Node: node, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e MapErrors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	nodeIDLine    = regexp.MustCompile(`^\s*(?:-\s+)?id:\s*(\d+)\s*$`)
+)
+
+// ParseMap reads a map definition, completes its roads and validates it. Every problem found is returned in
+// MapErrors. If strict, fields the Map doesn't know about are a problem as well.
+func ParseMap(data []byte, strict bool) (*Map, error) {
+	unmarshal := yaml.Unmarshal
+	if strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+
+	m := new(Map)
+	if err := unmarshal(data, m); err != nil {
+		return nil, yamlErrors(err)
+	}
+
+	lines := nodeLines(data)
+	errs := append(m.validateDefinition(lines), m.validateZones()...)
+	m.prepare()
+	errs = append(errs, m.validateGraph()...)
+	errs = append(errs, m.validatePOIs()...)
+
+	if len(errs) == 0 {
+		return m, nil
+	}
+
+	for i := range errs {
+		if errs[i].Line == 0 && len(lines[errs[i].Node]) > 0 {
+			errs[i].Line = lines[errs[i].Node][0]
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	return nil, errs
+}
+
+// yamlErrors converts the error returned by the YAML parser, which may contain multiple problems
+func yamlErrors(err error) MapErrors {
+	var msgs []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		msgs = typeErr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	errs := make(MapErrors, len(msgs))
+	for i, msg := range msgs {
+		errs[i].Message = msg
+		if match := yamlErrorLine.FindStringSubmatch(msg); match != nil {
+			errs[i].Line, _ = strconv.Atoi(match[1])
+			errs[i].Message = match[2]
+		}
+	}
+	return errs
+}
+
+// nodeLines returns the line numbers at which every node ID is defined
+func nodeLines(data []byte) map[uint32][]int {
+	lines := make(map[uint32][]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		match := nodeIDLine.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		if id, err := strconv.ParseUint(match[1], 10, 32); err == nil {
+			lines[uint32(id)] = append(lines[uint32(id)], line)
+		}
+	}
+	return lines
+}
+
+// validateDefinition checks the nodes and roads as they were defined, before completing them
+func (m *Map) validateDefinition(lines map[uint32][]int) MapErrors {
+	var errs MapErrors
+	if len(m.Nodes) == 0 {
+		errs.add(0, "map has no nodes")
+	}
+
+	ids := make(map[uint32]int)
+	for _, node := range m.Nodes {
+		if node.ID == 0 {
+			errs.add(0, "node at (%v, %v) has no ID", node.Location.X, node.Location.Y)
+			continue
+		}
+
+		occurrence := ids[node.ID]
+		ids[node.ID]++
+		if occurrence > 0 {
+			err := MapError{Node: node.ID, Message: "duplicate node ID"}
+			if occurrence < len(lines[node.ID]) {
+				err.Line = lines[node.ID][occurrence]
+				err.Message = fmt.Sprintf("duplicate node ID, first defined on line %d", lines[node.ID][0])
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	for _, node := range m.Nodes {
+		seen := make(map[uint32]struct{})
+		check := func(to uint32) {
+			switch _, exists := ids[to]; {
+			case to == node.ID:
+				errs.add(node.ID, "connected to itself")
+			case !exists:
+				errs.add(node.ID, "connected to unknown node %d", to)
+			}
+		}
+
+		for _, to := range node.ConnectedTo {
+			if _, ok := seen[to]; ok {
+				errs.add(node.ID, "connected to node %d more than once", to)
+			}
+			seen[to] = struct{}{}
+			check(to)
+		}
+
+		roads := make(map[uint32]struct{})
+		for _, road := range node.Roads {
+			if road.To == 0 {
+				errs.add(node.ID, "road without target node")
+				continue
+			}
+			if _, ok := roads[road.To]; ok {
+				errs.add(node.ID, "multiple roads to node %d", road.To)
+			}
+			roads[road.To] = struct{}{}
+			if _, ok := seen[road.To]; !ok {
+				check(road.To)
+			}
+
+			if road.Length < 0 {
+				errs.add(node.ID, "road to node %d has a negative length", road.To)
+			}
+			if road.SpeedLimit < 0 {
+				errs.add(node.ID, "road to node %d has a negative speed limit", road.To)
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateGraph checks whether every node can be reached from every other node
+func (m *Map) validateGraph() MapErrors {
+	var errs MapErrors
+
+	components := m.weakComponents()
+	if len(components) < 2 {
+		return append(errs, m.validateOneWays(m.Nodes)...)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	for _, island := range components[1:] {
+		ids := make([]string, len(island))
+		for i, node := range island {
+			ids[i] = strconv.FormatUint(uint64(node.ID), 10)
+		}
+		errs.add(island[0].ID, "island of %d node(s) not connected to the rest of the map: %s",
+			len(island), strings.Join(ids, ", "))
+	}
+
+	return append(errs, m.validateOneWays(components[0])...)
+}
+
+// validateOneWays checks whether every node in the (connected) nodes can be reached from every other one, which may
+// not be the case because of one-way roads
+func (m *Map) validateOneWays(nodes []*RouteNode) MapErrors {
+	var errs MapErrors
+
+	components := m.strongComponents(nodes)
+	if len(components) < 2 {
+		return errs
+	}
+
+	largest := 0
+	for i, c := range components {
+		if len(c) > len(components[largest]) {
+			largest = i
+		}
+	}
+	for i, c := range components {
+		if i == largest {
+			continue
+		}
+		for _, node := range c {
+			errs.add(node.ID, "can not reach, or can not be reached from, the rest of the map because of one-way roads")
+		}
+	}
+	return errs
+}
+
+// weakComponents groups the nodes which are connected to each other, ignoring the direction of roads
+func (m *Map) weakComponents() [][]*RouteNode {
+	neighbours := make(map[uint32][]*RouteNode)
+	for _, node := range m.Nodes {
+		for _, road := range node.Roads {
+			if to := m.Node(road.To); to != nil {
+				neighbours[node.ID] = append(neighbours[node.ID], to)
+				neighbours[to.ID] = append(neighbours[to.ID], node)
+			}
+		}
+	}
+
+	var components [][]*RouteNode
+	visited := make(map[uint32]struct{})
+	for _, node := range m.Nodes {
+		if _, ok := visited[node.ID]; ok {
+			continue
+		}
+
+		var component []*RouteNode
+		stack := []*RouteNode{node}
+		visited[node.ID] = struct{}{}
+		for len(stack) > 0 {
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			component = append(component, curr)
+			for _, n := range neighbours[curr.ID] {
+				if _, ok := visited[n.ID]; !ok {
+					visited[n.ID] = struct{}{}
+					stack = append(stack, n)
+				}
+			}
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// strongComponents uses Tarjan's algorithm to group the nodes which can all reach each other
+func (m *Map) strongComponents(nodes []*RouteNode) [][]*RouteNode {
+	var (
+		components [][]*RouteNode
+		index      = make(map[uint32]int)
+		lowLink    = make(map[uint32]int)
+		onStack    = make(map[uint32]bool)
+		stack      []*RouteNode
+		counter    int
+		connect    func(n *RouteNode)
+	)
+
+	connect = func(n *RouteNode) {
+		index[n.ID], lowLink[n.ID] = counter, counter
+		counter++
+		stack = append(stack, n)
+		onStack[n.ID] = true
+
+		for _, road := range n.Roads {
+			to := m.Node(road.To)
+			if to == nil {
+				continue
+			}
+			if _, visited := index[to.ID]; !visited {
+				connect(to)
+				if lowLink[to.ID] < lowLink[n.ID] {
+					lowLink[n.ID] = lowLink[to.ID]
+				}
+			} else if onStack[to.ID] && index[to.ID] < lowLink[n.ID] {
+				lowLink[n.ID] = index[to.ID]
+			}
+		}
+
+		if lowLink[n.ID] == index[n.ID] {
+			var component []*RouteNode
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top.ID] = false
+				component = append(component, top)
+				if top == n {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, visited := index[node.ID]; !visited {
+			connect(node)
+		}
+	}
+	return components
+}
@@ -0,0 +1,132 @@
+package dl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		data   string
+		// The problems expected, by line, node and part of their message
+		want []MapError
+	}{
+		{"valid", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    location: {x: 10, y: 0}
+`, nil},
+		{"dangling connectedTo", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    location: {x: 10, y: 0}
+    connectedTo: [9]
+`, []MapError{{6, 2, "connected to unknown node 9"}}},
+		{"duplicate IDs", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    location: {x: 10, y: 0}
+  - id: 1
+    location: {x: 20, y: 0}
+`, []MapError{{8, 1, "duplicate node ID, first defined on line 3"}}},
+		{"unknown field", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    colour: red
+    location: {x: 10, y: 0}
+`, []MapError{{7, 0, "colour not found"}}},
+		{"unknown field, not strict", false, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    colour: red
+    location: {x: 10, y: 0}
+`, nil},
+		{"island", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    location: {x: 10, y: 0}
+  - id: 3
+    location: {x: 50, y: 0}
+    connectedTo: [4]
+  - id: 4
+    location: {x: 60, y: 0}
+  - id: 5
+    location: {x: 20, y: 0}
+    connectedTo: [1]
+`, []MapError{{8, 3, "island of 2 node(s) not connected to the rest of the map: 3, 4"}}},
+		{"one-way", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [2]
+  - id: 2
+    location: {x: 10, y: 0}
+    roads:
+      - {to: 3, class: residential, oneWay: true}
+  - id: 3
+    location: {x: 20, y: 0}
+`, []MapError{{10, 3, "because of one-way roads"}}},
+		{"every problem", true, `
+nodes:
+  - id: 1
+    location: {x: 0, y: 0}
+    connectedTo: [1, 2]
+  - id: 2
+    location: {x: 10, y: 0}
+    connectedTo: [7]
+  - id: 2
+    location: {x: 20, y: 0}
+`, []MapError{
+			{3, 1, "connected to itself"},
+			{6, 2, "connected to unknown node 7"},
+			{9, 2, "duplicate node ID, first defined on line 6"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMap([]byte(tt.data), tt.strict)
+			if tt.want == nil {
+				if err != nil || m == nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var errs MapErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected MapErrors, got %v", err)
+			}
+			if len(errs) != len(tt.want) {
+				t.Fatalf("got %d problems, expected %d:\n%v", len(errs), len(tt.want), errs)
+			}
+			for i, want := range tt.want {
+				got := errs[i]
+				if got.Line != want.Line || got.Node != want.Node || !strings.Contains(got.Message, want.Message) {
+					t.Errorf("got %q, expected %q", got.Error(), want.Error())
+				}
+			}
+		})
+	}
+}
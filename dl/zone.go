@@ -0,0 +1,375 @@
+package dl
+
+import (
+	"image/color"
+	"log"
+	"math/rand"
+	"sort"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+	"github.com/luxengine/math"
+)
+
+const zoneViewKey = "zone-viewing-key"
+
+// ZoneKind indicates what a zone is used for
+type ZoneKind uint8
+
+const (
+	ZonePrecinct ZoneKind = iota
+	ZoneBeat
+	ZoneHotspot
+)
+
+var zoneKindNames = nameTable[ZoneKind]{typeName: "ZoneKind", what: "zone kind", names: map[ZoneKind]string{
+	ZonePrecinct: "precinct",
+	ZoneBeat:     "beat",
+	ZoneHotspot:  "hotspot",
+}}
+
+func (k ZoneKind) String() string {
+	return zoneKindNames.name(k)
+}
+
+func ParseZoneKind(s string) (ZoneKind, error) {
+	return zoneKindNames.parse(s)
+}
+
+func (k ZoneKind) MarshalYAML() (interface{}, error) {
+	return k.String(), nil
+}
+
+func (k *ZoneKind) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return zoneKindNames.unmarshalYAML(unmarshal, k)
+}
+
+// Zone is a named area of the map, like a precinct, a patrol beat or a high-crime area. Zones may overlap.
+type Zone struct {
+	Name    string       `yaml:"name"`
+	Kind    ZoneKind     `yaml:"kind"`
+	Polygon []engo.Point `yaml:"polygon"`
+	// IncidentRate is how many incidents happen in the zone, relative to the other zones
+	IncidentRate float32 `yaml:"incidentRate,omitempty"`
+
+	Statistics ZoneStatistics `yaml:"-"`
+}
+
+// ZoneStatistics counts the incidents that happened in a zone
+type ZoneStatistics struct {
+	Reported, Resolved, Failed int
+}
+
+// count adds an incident with the outcome, which is like Incident.Resolved: 0 if it was just reported
+func (s *ZoneStatistics) count(outcome int) {
+	switch {
+	case outcome < 0:
+		s.Failed++
+	case outcome > 0:
+		s.Resolved++
+	default:
+		s.Reported++
+	}
+}
+
+// Contains indicates whether or not the point is inside the zone
+func (z *Zone) Contains(p engo.Point) bool {
+	inside := false
+	for i, j := 0, len(z.Polygon)-1; i < len(z.Polygon); j, i = i, i+1 {
+		a, b := z.Polygon[i], z.Polygon[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Area returns the size of the zone
+func (z *Zone) Area() float32 {
+	return math.Abs(signedArea(z.Polygon))
+}
+
+// Center returns the centroid of the zone
+func (z *Zone) Center() engo.Point {
+	var c engo.Point
+	area := signedArea(z.Polygon)
+	if area == 0 {
+		for _, p := range z.Polygon {
+			c.X, c.Y = c.X+p.X/float32(len(z.Polygon)), c.Y+p.Y/float32(len(z.Polygon))
+		}
+		return c
+	}
+	for i := range z.Polygon {
+		a, b := z.Polygon[i], z.Polygon[(i+1)%len(z.Polygon)]
+		cross := a.X*b.Y - b.X*a.Y
+		c.X += (a.X + b.X) * cross
+		c.Y += (a.Y + b.Y) * cross
+	}
+	c.X, c.Y = c.X/(6*area), c.Y/(6*area)
+	return c
+}
+
+// Bounds returns the top left and bottom right corners of the smallest rectangle containing the zone
+func (z *Zone) Bounds() (min, max engo.Point) {
+	if len(z.Polygon) == 0 {
+		return
+	}
+	min, max = z.Polygon[0], z.Polygon[0]
+	for _, p := range z.Polygon {
+		min.X, min.Y = math.Min(min.X, p.X), math.Min(min.Y, p.Y)
+		max.X, max.Y = math.Max(max.X, p.X), math.Max(max.Y, p.Y)
+	}
+	return
+}
+
+// randomPoint returns a random point inside the zone
+func (z *Zone) randomPoint(rng *rand.Rand) engo.Point {
+	min, max := z.Bounds()
+	for i := 0; i < 100; i++ {
+		p := engo.Point{X: min.X + rng.Float32()*(max.X-min.X), Y: min.Y + rng.Float32()*(max.Y-min.Y)}
+		if z.Contains(p) {
+			return p
+		}
+	}
+	return z.Center()
+}
+
+// signedArea is positive if the corners of the polygon are in counter-clockwise order
+func signedArea(polygon []engo.Point) float32 {
+	var area float32
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	return area / 2
+}
+
+// Zone returns the zone with the given name, or nil if there is none
+func (m *Map) Zone(name string) *Zone {
+	for _, z := range m.Zones {
+		if z.Name == name {
+			return z
+		}
+	}
+	return nil
+}
+
+// ZonesAt returns all zones containing the point, from the smallest to the largest
+func (m *Map) ZonesAt(p engo.Point) []*Zone {
+	var zones []*Zone
+	for _, z := range m.Zones {
+		if z.Contains(p) {
+			zones = append(zones, z)
+		}
+	}
+	sort.SliceStable(zones, func(i, j int) bool { return zones[i].Area() < zones[j].Area() })
+	return zones
+}
+
+// ZoneAt returns the smallest zone containing the point, or nil if there is none
+func (m *Map) ZoneAt(p engo.Point) *Zone {
+	if zones := m.ZonesAt(p); len(zones) > 0 {
+		return zones[0]
+	}
+	return nil
+}
+
+// NodesIn returns all nodes inside the zone
+func (m *Map) NodesIn(z *Zone) []*RouteNode {
+	min, max := z.Bounds()
+	center := engo.Point{X: (min.X + max.X) / 2, Y: (min.Y + max.Y) / 2}
+	var nodes []*RouteNode
+	for _, node := range m.NodesWithin(center, center.PointDistance(max)) {
+		if z.Contains(node.Location) {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// RandomIncidentLocation picks where on the road an incident happens: in one of the zones, according to their incident
+// rates, or anywhere on the map if no zone has an incident rate
+func (m *Map) RandomIncidentLocation(rng *rand.Rand) engo.Point {
+	var total float32
+	for _, z := range m.Zones {
+		total += z.IncidentRate
+	}
+	if total > 0 {
+		pick := rng.Float32() * total
+		for _, z := range m.Zones {
+			if pick -= z.IncidentRate; pick < 0 && z.IncidentRate > 0 {
+				return m.SnapToRoad(z.randomPoint(rng)).Point
+			}
+		}
+	}
+	if len(m.Nodes) == 0 {
+		return engo.Point{}
+	}
+	return m.Nodes[rng.Intn(len(m.Nodes))].Location
+}
+
+// countIncident adds the incident to the statistics of every zone it happened in
+func (m *Map) countIncident(location engo.Point, outcome int) {
+	for _, z := range m.ZonesAt(location) {
+		z.Statistics.count(outcome)
+	}
+}
+
+// AssignedUnits returns all units assigned to the zone
+func (z *Zone) AssignedUnits() []*PoliceComponent {
+	var units []*PoliceComponent
+	for _, unit := range police {
+		if unit.Zone == z {
+			units = append(units, unit.PoliceComponent)
+		}
+	}
+	return units
+}
+
+// validateZones checks the zones of the map
+func (m *Map) validateZones() MapErrors {
+	var errs MapErrors
+	names := make(map[string]struct{})
+	for i, z := range m.Zones {
+		if z.Name == "" {
+			errs.add(0, "zone %d has no name", i+1)
+		} else if _, ok := names[z.Name]; ok {
+			errs.add(0, "zone %q is defined more than once", z.Name)
+		}
+		names[z.Name] = struct{}{}
+
+		if len(z.Polygon) < 3 {
+			errs.add(0, "zone %q has %d corners, but needs at least 3", z.Name, len(z.Polygon))
+		}
+		if z.IncidentRate < 0 {
+			errs.add(0, "zone %q has a negative incident rate", z.Name)
+		}
+	}
+	return errs
+}
+
+// triangulate splits the polygon into triangles by clipping its ears, returning the corners of every triangle
+func triangulate(polygon []engo.Point) []engo.Point {
+	corners := append([]engo.Point(nil), polygon...)
+	if signedArea(corners) < 0 {
+		for i, j := 0, len(corners)-1; i < j; i, j = i+1, j-1 {
+			corners[i], corners[j] = corners[j], corners[i]
+		}
+	}
+
+	cross := func(o, a, b engo.Point) float32 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	isEar := func(i int) bool {
+		a, b, c := corners[(i+len(corners)-1)%len(corners)], corners[i], corners[(i+1)%len(corners)]
+		if cross(a, b, c) <= 0 {
+			return false
+		}
+		for _, p := range corners {
+			if p != a && p != b && p != c && cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	var triangles []engo.Point
+	for len(corners) > 3 {
+		ear := -1
+		for i := range corners {
+			if isEar(i) {
+				ear = i
+				break
+			}
+		}
+		if ear < 0 {
+			// The polygon intersects itself, so just clip whatever is left
+			ear = 0
+		}
+		prev, next := corners[(ear+len(corners)-1)%len(corners)], corners[(ear+1)%len(corners)]
+		triangles = append(triangles, prev, corners[ear], next)
+		corners = append(corners[:ear], corners[ear+1:]...)
+	}
+	if len(corners) == 3 {
+		triangles = append(triangles, corners...)
+	}
+	return triangles
+}
+
+// ZoneOverlaySystem draws the zones of the map with their names, which can be toggled with F4
+type ZoneOverlaySystem struct {
+	Map *Map
+
+	rs       *common.RenderSystem
+	visible  bool
+	entities []*polygonEntity
+	labels   []*ui.Label
+}
+
+func (z *ZoneOverlaySystem) New(w *ecs.World) {
+	for _, system := range w.Systems() {
+		if rs, ok := system.(*common.RenderSystem); ok {
+			z.rs = rs
+		}
+	}
+	engo.Input.RegisterButton(zoneViewKey, engo.F4)
+
+	fnt := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.White,
+		Size: 32,
+	}
+	if err := fnt.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+
+	// Larger zones are drawn first, so the smaller ones are drawn on top of them
+	zones := append([]*Zone(nil), z.Map.Zones...)
+	sort.SliceStable(zones, func(i, j int) bool { return zones[i].Area() > zones[j].Area() })
+	for i, zone := range zones {
+		e := newPolygonEntity(triangulate(zone.Polygon), ui.ZoneColors[zone.Kind%ZoneKind(len(ui.ZoneColors))])
+		if e == nil {
+			continue
+		}
+		// Zones are drawn below the roads
+		e.RenderComponent.SetZIndex(-1 + float32(i)/float32(len(zones)))
+		z.entities = append(z.entities, e)
+
+		label := &ui.Label{
+			BasicEntity:     ecs.NewBasic(),
+			Font:            fnt,
+			SpaceComponent:  common.SpaceComponent{Position: zone.Center(), Width: 200, Height: 16},
+			RenderComponent: common.RenderComponent{Scale: engo.Point{0.5, 0.5}},
+		}
+		label.SetText(zone.Name)
+		label.SetZIndex(2)
+		z.labels = append(z.labels, label)
+	}
+
+	for _, e := range z.entities {
+		z.rs.Add(&e.BasicEntity, &e.RenderComponent, &e.SpaceComponent)
+	}
+	for _, label := range z.labels {
+		z.rs.Add(&label.BasicEntity, &label.RenderComponent, &label.SpaceComponent)
+	}
+	z.visible = true
+}
+
+func (z *ZoneOverlaySystem) Remove(b ecs.BasicEntity) {}
+
+func (z *ZoneOverlaySystem) Update(dt float32) {
+	if !engo.Input.Button(zoneViewKey).JustPressed() {
+		return
+	}
+	z.visible = !z.visible
+	log.Println("ZoneOverlay:", z.visible)
+	for _, e := range z.entities {
+		e.Hidden = !z.visible
+	}
+	for _, label := range z.labels {
+		label.Hidden = !z.visible
+	}
+}
@@ -0,0 +1,129 @@
+package dl
+
+import (
+	"math/rand"
+	"testing"
+
+	"engo.io/engo"
+	"github.com/luxengine/math"
+)
+
+// lShape is a concave zone of 200 by 200, of which the quarter from (100, 0) to (200, 100) is cut out. Its corners
+// are in clockwise order.
+var lShape = []engo.Point{{X: 0, Y: 0}, {X: 0, Y: 200}, {X: 200, Y: 200}, {X: 200, Y: 100}, {X: 100, Y: 100}, {X: 100, Y: 0}}
+
+func TestZoneContains(t *testing.T) {
+	z := &Zone{Polygon: lShape}
+	tests := []struct {
+		name   string
+		p      engo.Point
+		inside bool
+	}{
+		{"bottom arm", engo.Point{X: 50, Y: 50}, true},
+		{"top arm", engo.Point{X: 150, Y: 150}, true},
+		{"corner between the arms", engo.Point{X: 50, Y: 150}, true},
+		{"cut out corner", engo.Point{X: 150, Y: 50}, false},
+		{"left of the zone", engo.Point{X: -10, Y: 100}, false},
+		{"above the zone", engo.Point{X: 50, Y: 250}, false},
+		{"in line with an edge", engo.Point{X: 250, Y: 100}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if z.Contains(tt.p) != tt.inside {
+				t.Fatalf("Contains(%v) is %v", tt.p, !tt.inside)
+			}
+		})
+	}
+
+	if (&Zone{}).Contains(engo.Point{}) {
+		t.Fatal("a zone without corners contains a point")
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	counterClockwise := make([]engo.Point, len(lShape))
+	for i, p := range lShape {
+		counterClockwise[len(lShape)-1-i] = p
+	}
+	z := &Zone{Polygon: lShape}
+
+	for name, polygon := range map[string][]engo.Point{"clockwise": lShape, "counter-clockwise": counterClockwise} {
+		t.Run(name, func(t *testing.T) {
+			triangles := triangulate(polygon)
+			if len(triangles) != 3*(len(polygon)-2) {
+				t.Fatalf("%d corners, expected %d triangles", len(triangles), len(polygon)-2)
+			}
+
+			// The triangles are counter-clockwise, within the polygon, and cover all of it
+			var area float32
+			for i := 0; i < len(triangles); i += 3 {
+				triangle := triangles[i : i+3]
+				a := signedArea(triangle)
+				if a <= 0 {
+					t.Fatalf("triangle %v isn't counter-clockwise", triangle)
+				}
+				center := engo.Point{
+					X: (triangle[0].X + triangle[1].X + triangle[2].X) / 3,
+					Y: (triangle[0].Y + triangle[1].Y + triangle[2].Y) / 3,
+				}
+				if !z.Contains(center) {
+					t.Fatalf("triangle %v is outside of the polygon", triangle)
+				}
+				area += a
+			}
+			if math.Abs(area-z.Area()) > 1e-2 {
+				t.Fatalf("the triangles cover %.2f, expected %.2f", area, z.Area())
+			}
+		})
+	}
+
+	if triangles := triangulate(lShape[:2]); len(triangles) != 0 {
+		t.Fatalf("a line was split into %v", triangles)
+	}
+}
+
+func TestRandomIncidentLocation(t *testing.T) {
+	m := RandomMap(10, 10, 100, 100)
+	m.Initialize()
+	rng := rand.New(rand.NewSource(1))
+
+	// Without incident rates, incidents happen at any node
+	for i := 0; i < 100; i++ {
+		p := m.RandomIncidentLocation(rng)
+		if n := m.NearestNode(p); n == nil || n.Location != p {
+			t.Fatalf("incident at %v, which isn't a node", p)
+		}
+	}
+
+	square := func(x, y float32) []engo.Point {
+		return []engo.Point{{X: x, Y: y}, {X: x + 200, Y: y}, {X: x + 200, Y: y + 200}, {X: x, Y: y + 200}}
+	}
+	m.Zones = []*Zone{
+		{Name: "Busy", Polygon: square(100, 100), IncidentRate: 3},
+		{Name: "Quiet", Polygon: square(600, 600), IncidentRate: 1},
+		{Name: "Calm", Polygon: square(100, 600)},
+	}
+
+	// Incidents happen on the road, in the zones with an incident rate, as often as their rates say
+	counts := make(map[string]int)
+	const incidents = 4000
+	for i := 0; i < incidents; i++ {
+		p := m.RandomIncidentLocation(rng)
+		if snapped := m.SnapToRoad(p).Point; snapped.PointDistance(p) > 1e-3 {
+			t.Fatalf("incident at %v, which isn't on a road", p)
+		}
+		for _, z := range m.Zones {
+			min, max := z.Bounds()
+			if p.X >= min.X && p.X <= max.X && p.Y >= min.Y && p.Y <= max.Y {
+				counts[z.Name]++
+			}
+		}
+	}
+	if counts["Busy"]+counts["Quiet"] != incidents || counts["Calm"] != 0 {
+		t.Fatalf("incidents happened outside of the zones with an incident rate: %v", counts)
+	}
+	if busy := float32(counts["Busy"]) / incidents; busy < 0.7 || busy > 0.8 {
+		t.Fatalf("%.2f of the incidents happened in the busy zone, expected 0.75", busy)
+	}
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/dl"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+)
+
+const (
+	editorUndoKey     = "editor-undo"
+	editorRedoKey     = "editor-redo"
+	editorDeleteKey   = "editor-delete"
+	editorDeselectKey = "editor-deselect"
+	switchSceneKey    = "switch-scene"
+
+	toolbarButtonWidth float32 = 130
+)
+
+var (
+	editorRoadColors = map[dl.RoadClass]color.Color{
+		dl.RoadResidential: ui.RoadColor,
+		dl.RoadAlley:       color.NRGBA{180, 180, 180, 255},
+		dl.RoadArterial:    color.NRGBA{255, 220, 100, 255},
+		dl.RoadHighway:     color.NRGBA{255, 140, 60, 255},
+	}
+	editorSpeedLimits = []float32{0, 30, 50, 80, 100, 130}
+)
+
+type editorTool uint8
+
+const (
+	toolMove editorTool = iota
+	toolRoad
+	toolSelectRoad
+	toolDelete
+)
+
+var editorToolNames = map[editorTool]string{
+	toolMove:       "Move / add",
+	toolRoad:       "Draw roads",
+	toolSelectRoad: "Select road",
+	toolDelete:     "Delete",
+}
+
+type Editor struct{}
+
+var TheEditor = &Editor{}
+
+func (e *Editor) Preload() {
+	TheGame.Preload()
+}
+
+func (e *Editor) Setup(w *ecs.World) {
+	common.SetBackground(color.NRGBA{60, 60, 60, 255})
+
+	w.AddSystem(&common.CameraSystem{})
+	w.AddSystem(&common.RenderSystem{})
+	w.AddSystem(&common.MouseSystem{})
+	w.AddSystem(common.NewKeyboardScroller(KeyboardScrollSpeed, engo.DefaultHorizontalAxis, engo.DefaultVerticalAxis))
+	w.AddSystem(&common.MouseZoomer{ZoomSpeed})
+	w.AddSystem(&SceneSwitcher{Scene: TheGame.Type()})
+
+	m := TheGame.currentMap()
+	m.Initialize()
+	w.AddSystem(&EditorSystem{Editor: dl.NewMapEditor(m), SaveAs: TheGame.saveFile()})
+	w.AddSystem(&RoadRenderSystem{Map: m, Color: func(road *dl.Road) color.Color {
+		return editorRoadColors[road.Class]
+	}})
+}
+
+func (e *Editor) Type() string {
+	return "EditorScene"
+}
+
+// SceneSwitcher switches to the other scene when F10 is pressed
+type SceneSwitcher struct {
+	Scene string
+}
+
+func (s *SceneSwitcher) New(w *ecs.World) {
+	engo.Input.RegisterButton(switchSceneKey, engo.F10)
+}
+
+func (s *SceneSwitcher) Remove(b ecs.BasicEntity) {}
+
+func (s *SceneSwitcher) Update(dt float32) {
+	if engo.Input.Button(switchSceneKey).JustPressed() {
+		if err := engo.SetSceneByName(s.Scene, true); err != nil {
+			log.Println("Unable to switch scenes:", err)
+		}
+	}
+}
+
+type editorNode struct {
+	ecs.BasicEntity
+	common.RenderComponent
+	common.SpaceComponent
+}
+
+// EditorSystem lets the player edit the map: place, drag and delete nodes, draw and delete roads, and save the result.
+// The roads themselves are drawn by the RoadRenderSystem.
+type EditorSystem struct {
+	Editor *dl.MapEditor
+	// SaveAs is the file the map is written to
+	SaveAs string
+
+	world *ecs.World
+	rs    *common.RenderSystem
+
+	nodes map[uint32]*editorNode
+
+	tool     editorTool
+	road     dl.Road
+	selected *dl.RouteNode
+	// selectedRoad are the nodes at the ends of the selected road, if any
+	selectedRoad *[2]*dl.RouteNode
+	dragging     bool
+	// dragOffset is where the node was grabbed, relative to the mouse
+	dragOffset engo.Point
+
+	mouseTracker common.MouseComponent
+	toolbar      []*ui.Button
+	classButton  *ui.Button
+	oneWayButton *ui.Button
+	speedButton  *ui.Button
+	toolButtons  map[editorTool]*ui.Button
+	status       ui.Label
+}
+
+func (es *EditorSystem) New(w *ecs.World) {
+	es.world = w
+	es.nodes = make(map[uint32]*editorNode)
+	es.toolButtons = make(map[editorTool]*ui.Button)
+
+	engo.Input.RegisterButton(editorUndoKey, engo.Z)
+	engo.Input.RegisterButton(editorRedoKey, engo.Y)
+	engo.Input.RegisterButton(editorDeleteKey, engo.Delete, engo.Backspace)
+	engo.Input.RegisterButton(editorDeselectKey, engo.Escape)
+
+	fnt := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.Black,
+		Size: float64(ui.TooltipLineHeight),
+	}
+	if err := fnt.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+
+	// The first row contains the tools, the second one the attributes of the selected road (or of new roads if
+	// none is selected) followed by undo, redo and save
+	for i, tool := range []editorTool{toolMove, toolRoad, toolSelectRoad, toolDelete} {
+		tool := tool
+		es.toolButtons[tool] = es.addButton(fnt, editorToolNames[tool], i, 0, func() { es.setTool(tool) })
+	}
+	es.addButton(fnt, "Undo (Z)", 3, 1, es.undo)
+	es.addButton(fnt, "Redo (Y)", 4, 1, es.redo)
+	es.addButton(fnt, "Save", 5, 1, es.save)
+	es.classButton = es.addButton(fnt, "", 0, 1, func() {
+		es.changeRoad(func(road *dl.Road) { road.Class = (road.Class + 1) % (dl.RoadHighway + 1) })
+	})
+	es.oneWayButton = es.addButton(fnt, "", 1, 1, func() {
+		es.changeRoad(func(road *dl.Road) { road.OneWay = !road.OneWay })
+	})
+	es.speedButton = es.addButton(fnt, "", 2, 1, func() {
+		es.changeRoad(func(road *dl.Road) { road.SpeedLimit = nextSpeedLimit(road.SpeedLimit) })
+	})
+
+	statusFont := &common.Font{
+		URL:  "fonts/Roboto-Regular.ttf",
+		FG:   color.White,
+		Size: float64(ui.TooltipLineHeight),
+	}
+	if err := statusFont.CreatePreloaded(); err != nil {
+		panic(err)
+	}
+	es.status = ui.Label{
+		BasicEntity:    ecs.NewBasic(),
+		Font:           statusFont,
+		SpaceComponent: common.SpaceComponent{Position: engo.Point{4, 2*ui.TooltipLineHeight + 4}, Width: 400, Height: ui.TooltipLineHeight},
+	}
+	es.status.SetText("Press F10 to play")
+	es.status.SetShader(common.TextHUDShader)
+
+	es.mouseTracker.Track = true
+	mouseTrackerBasic := ecs.NewBasic()
+
+	for _, system := range w.Systems() {
+		switch sys := system.(type) {
+		case *common.RenderSystem:
+			es.rs = sys
+			for _, b := range es.toolbar {
+				sys.Add(&b.Label.BasicEntity, &b.Label.RenderComponent, &b.Label.SpaceComponent)
+				sys.Add(&b.Graphic.BasicEntity, &b.Graphic.RenderComponent, &b.Graphic.SpaceComponent)
+			}
+			sys.Add(&es.status.BasicEntity, &es.status.RenderComponent, &es.status.SpaceComponent)
+		case *common.MouseSystem:
+			for _, b := range es.toolbar {
+				sys.Add(&b.Graphic.BasicEntity, &b.MouseComponent, &b.Graphic.SpaceComponent, &b.Graphic.RenderComponent)
+			}
+			sys.Add(&mouseTrackerBasic, &es.mouseTracker, nil, nil)
+		}
+	}
+
+	es.updateToolbar()
+	es.rebuild()
+}
+
+func (es *EditorSystem) addButton(fnt *common.Font, label string, col, row int, onClick func()) *ui.Button {
+	x, y := float32(col)*toolbarButtonWidth, float32(row)*ui.TooltipLineHeight
+
+	but := ui.NewButton(fnt, label)
+	but.OnClick = func(*ui.Button) { onClick() }
+	but.OnMouseOver = func(b *ui.Button) {
+		b.Graphic.Color = ui.TooltipColorHover
+		ui.StartHovering(b.Graphic.ID())
+	}
+	but.OnMouseOut = func(b *ui.Button) {
+		b.Graphic.Color = ui.TooltipColor
+		ui.StopHovering(b.Graphic.ID())
+	}
+	if but.Label.Font == nil {
+		but.Label.Font = fnt
+		but.Label.SetText(" ")
+	}
+	but.Label.Position = engo.Point{x + 4, y}
+	but.Label.Width = toolbarButtonWidth
+	but.Label.Height = ui.TooltipLineHeight
+	but.Label.Scale = engo.Point{0.7, 0.7}
+	but.Label.SetZIndex(10)
+	but.Label.SetShader(common.TextHUDShader)
+	but.Graphic.Color = ui.TooltipColor
+	but.Graphic.Drawable = ui.TooltipGraphic
+	but.Graphic.Position = engo.Point{x, y}
+	but.Graphic.Width = toolbarButtonWidth
+	but.Graphic.Height = ui.TooltipLineHeight
+	but.Graphic.SetZIndex(9)
+	but.Graphic.RenderComponent.SetShader(common.HUDShader)
+
+	es.toolbar = append(es.toolbar, but)
+	return but
+}
+
+func (es *EditorSystem) setTool(tool editorTool) {
+	es.tool = tool
+	es.selected = nil
+	es.selectedRoad = nil
+	es.updateToolbar()
+	es.updateNodeColors()
+}
+
+// nextSpeedLimit returns the speed limit following the given one in editorSpeedLimits
+func nextSpeedLimit(speed float32) float32 {
+	for i, s := range editorSpeedLimits {
+		if s == speed {
+			return editorSpeedLimits[(i+1)%len(editorSpeedLimits)]
+		}
+	}
+	return editorSpeedLimits[0]
+}
+
+// attributes returns the road the attribute buttons apply to: the selected road, or the one new roads are copied from
+func (es *EditorSystem) attributes() dl.Road {
+	if es.selectedRoad != nil {
+		a, b := es.selectedRoad[0], es.selectedRoad[1]
+		if road := a.Road(b.ID); road != nil {
+			return *road
+		}
+		if road := b.Road(a.ID); road != nil {
+			return *road
+		}
+	}
+	return es.road
+}
+
+// changeRoad changes the attributes of the selected road, or of new roads if no road is selected
+func (es *EditorSystem) changeRoad(change func(road *dl.Road)) {
+	if es.selectedRoad == nil {
+		change(&es.road)
+		es.updateToolbar()
+		return
+	}
+
+	attrs := es.attributes()
+	change(&attrs)
+	if es.Editor.SetRoadAttributes(es.selectedRoad[0], es.selectedRoad[1], attrs) {
+		es.rebuild()
+	}
+	es.updateToolbar()
+}
+
+func (es *EditorSystem) updateToolbar() {
+	for tool, b := range es.toolButtons {
+		text := editorToolNames[tool]
+		if tool == es.tool {
+			text = "> " + text
+		}
+		b.Label.SetText(text)
+	}
+
+	road := es.attributes()
+	es.classButton.Label.SetText("Class: " + road.Class.String())
+	if road.OneWay {
+		es.oneWayButton.Label.SetText("One-way: yes")
+	} else {
+		es.oneWayButton.Label.SetText("One-way: no")
+	}
+	if road.SpeedLimit > 0 {
+		es.speedButton.Label.SetText(fmt.Sprintf("Speed: %.0f", road.SpeedLimit))
+	} else {
+		es.speedButton.Label.SetText("Speed: default")
+	}
+}
+
+func (es *EditorSystem) setStatus(format string, args ...interface{}) {
+	es.status.SetText(fmt.Sprintf(format, args...))
+}
+
+func (es *EditorSystem) Remove(b ecs.BasicEntity) {}
+
+func (es *EditorSystem) Update(dt float32) {
+	var toolbarUsed bool
+	for _, b := range es.toolbar {
+		if b.Clicked {
+			b.OnClick(b)
+			toolbarUsed = true
+		}
+		if b.Enter {
+			b.OnMouseOver(b)
+		} else if b.Leave {
+			b.OnMouseOut(b)
+		}
+	}
+
+	switch {
+	case es.dragging:
+		// Editing keys are ignored while dragging, as the dragged node has to stay selected
+	case engo.Input.Button(editorUndoKey).JustPressed():
+		es.undo()
+	case engo.Input.Button(editorRedoKey).JustPressed():
+		es.redo()
+	case engo.Input.Button(editorDeleteKey).JustPressed() && es.selected != nil:
+		es.Editor.DeleteNode(es.selected)
+		es.selected = nil
+		es.rebuild()
+	case engo.Input.Button(editorDeleteKey).JustPressed() && es.selectedRoad != nil:
+		es.Editor.DeleteRoad(es.selectedRoad[0], es.selectedRoad[1])
+		es.selectedRoad = nil
+		es.rebuild()
+		es.updateToolbar()
+	case engo.Input.Button(editorDeselectKey).JustPressed():
+		es.selected = nil
+		es.selectedRoad = nil
+		es.updateToolbar()
+		es.updateNodeColors()
+	}
+
+	mouse := engo.Point{es.mouseTracker.MouseX, es.mouseTracker.MouseY}
+	if es.dragging {
+		switch engo.Input.Mouse.Action {
+		case engo.Release:
+			es.dragging = false
+		default:
+			to := engo.Point{mouse.X + es.dragOffset.X, mouse.Y + es.dragOffset.Y}
+			if to != es.selected.Location {
+				es.Editor.MoveNode(es.selected, to)
+				es.updateNode(es.selected)
+			}
+		}
+		return
+	}
+
+	// Clicks on the toolbar are not meant for the map
+	if toolbarUsed || engo.Input.Mouse.Y < 2*ui.TooltipLineHeight ||
+		engo.Input.Mouse.Action != engo.Press || engo.Input.Mouse.Button != engo.MouseButtonLeft {
+		return
+	}
+
+	node := es.nodeAt(mouse)
+	switch es.tool {
+	case toolMove:
+		switch {
+		case node != nil:
+			es.selected = node
+			es.dragging = true
+			es.dragOffset = engo.Point{node.Location.X - mouse.X, node.Location.Y - mouse.Y}
+		case es.selected != nil:
+			// Keep drawing from the selected node
+			added := es.Editor.AddNode(mouse)
+			es.Editor.Connect(es.selected, added, es.road)
+			es.selected = added
+			es.rebuild()
+		default:
+			es.selected = es.Editor.AddNode(mouse)
+			es.rebuild()
+		}
+	case toolRoad:
+		switch {
+		case node == nil:
+		case es.selected == nil || es.selected == node:
+			es.selected = node
+		default:
+			es.Editor.Connect(es.selected, node, es.road)
+			es.selected = node
+			es.rebuild()
+		}
+	case toolSelectRoad:
+		es.selectedRoad = nil
+		if seg, ok := es.Editor.Map.NearestRoad(mouse); ok && seg.Distance(mouse) < ui.NodeSize {
+			es.selectedRoad = &[2]*dl.RouteNode{seg.From, seg.To}
+			if seg.Road.Name != "" {
+				es.setStatus("Selected %s", seg.Road.Name)
+			}
+		}
+		es.updateToolbar()
+	case toolDelete:
+		if node != nil {
+			es.Editor.DeleteNode(node)
+			es.rebuild()
+		} else if seg, ok := es.Editor.Map.NearestRoad(mouse); ok && seg.Distance(mouse) < ui.NodeSize {
+			es.Editor.DeleteRoad(seg.From, seg.To)
+			es.rebuild()
+		}
+	}
+	es.updateNodeColors()
+}
+
+// nodeAt returns the node at the location, if any
+func (es *EditorSystem) nodeAt(p engo.Point) *dl.RouteNode {
+	node := es.Editor.Map.NearestNode(p)
+	if node == nil || node.Location.PointDistance(p) > ui.NodeSize {
+		return nil
+	}
+	return node
+}
+
+func (es *EditorSystem) undo() {
+	if !es.Editor.Undo() {
+		es.setStatus("Nothing to undo")
+		return
+	}
+	es.selected = nil
+	es.selectedRoad = nil
+	es.rebuild()
+	es.updateToolbar()
+}
+
+func (es *EditorSystem) redo() {
+	if !es.Editor.Redo() {
+		es.setStatus("Nothing to redo")
+		return
+	}
+	es.selected = nil
+	es.selectedRoad = nil
+	es.rebuild()
+	es.updateToolbar()
+}
+
+func (es *EditorSystem) save() {
+	f, err := os.Create(es.SaveAs)
+	if err != nil {
+		es.setStatus("Unable to save: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if err := es.Editor.Save(f); err != nil {
+		es.setStatus("Unable to save: %v", err)
+		return
+	}
+	es.setStatus("Saved to %s", es.SaveAs)
+}
+
+// rebuild recreates the entities of all nodes, after the map has been changed
+func (es *EditorSystem) rebuild() {
+	for _, n := range es.nodes {
+		es.world.RemoveEntity(n.BasicEntity)
+	}
+	es.nodes = make(map[uint32]*editorNode)
+
+	m := es.Editor.Map
+	for _, node := range m.Nodes {
+		n := &editorNode{
+			BasicEntity:     ecs.NewBasic(),
+			RenderComponent: common.RenderComponent{Drawable: ui.NodeGraphic, Color: ui.NodeColor},
+			SpaceComponent:  common.SpaceComponent{Width: ui.NodeSize, Height: ui.NodeSize},
+		}
+		n.SetZIndex(2)
+		es.nodes[node.ID] = n
+		es.rs.Add(&n.BasicEntity, &n.RenderComponent, &n.SpaceComponent)
+		es.updateNode(node)
+	}
+	es.updateNodeColors()
+
+	status := fmt.Sprintf("%d nodes", len(m.Nodes))
+	if es.Editor.Modified() {
+		status += " (modified)"
+	}
+	es.setStatus("%s - press F10 to play", status)
+}
+
+// updateNode positions the entity of the node, after it has been moved
+func (es *EditorSystem) updateNode(node *dl.RouteNode) {
+	if n, ok := es.nodes[node.ID]; ok {
+		n.Position = engo.Point{node.Location.X - ui.NodeSize/2, node.Location.Y - ui.NodeSize/2}
+	}
+}
+
+func (es *EditorSystem) updateNodeColors() {
+	for id, n := range es.nodes {
+		switch {
+		case es.selected != nil && es.selected.ID == id,
+			es.selectedRoad != nil && (es.selectedRoad[0].ID == id || es.selectedRoad[1].ID == id):
+			n.Color = ui.PoliceColorSelected
+		default:
+			n.Color = ui.NodeColor
+		}
+	}
+}
+
+// saveFile returns the file edited maps are saved to: the map file itself, or a new .map file next to it
+func (g *Game) saveFile() string {
+	if g.MapURL == randomMap {
+		return filepath.Join("assets", "maps", fmt.Sprintf("generated-%d.map", g.Seed))
+	}
+
+	name := filepath.Join("assets", g.MapURL)
+	if strings.HasSuffix(name, ".osm.pbf") {
+		name = strings.TrimSuffix(name, ".pbf")
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".map"
+}
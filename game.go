@@ -1,14 +1,16 @@
 package main
 
 import (
+	"flag"
 	"image/color"
+	"log"
+	"time"
 
 	"engo.io/ecs"
 	"engo.io/engo"
 	"engo.io/engo/common"
 	"github.com/EtienneBruines/ultimate-dispatcher/dl"
 	"github.com/EtienneBruines/ultimate-dispatcher/ui"
-	"github.com/luxengine/math"
 )
 
 const (
@@ -20,25 +22,85 @@ const (
 	ZoomSpeed           = -0.125
 )
 
+// randomMap can be used instead of a map file, to play on a procedurally generated map
+const randomMap = "random"
+
+var (
+	mapFlag  = flag.String("map", "maps/1.map", "the map to play on, or \""+randomMap+"\" for a procedurally generated one")
+	seedFlag = flag.Int64("seed", 0, "the seed for procedurally generated maps and patrols, 0 picks a random one")
+	hourFlag = flag.Float64("hour", 7, "the time of day (in hours) at which the game starts, which determines the traffic")
+	editFlag = flag.Bool("edit", false, "start in the map editor instead of the game")
+)
+
 type Game struct {
 	Paused bool
+
+	// MapURL is the map to play on, if it fails to load a procedurally generated one is used instead
+	MapURL string
+	// Seed is used to generate the map, if needed
+	Seed int64
+
+	// Map is shared with the editor, so changes made there can be played right away
+	Map *dl.Map
 }
 
 var TheGame = &Game{}
 
 func (g *Game) Preload() {
 	engo.Files.Register(".map", &dl.MapLoader{})
+	engo.Files.Register(".osm", &dl.OSMLoader{})
+	engo.Files.Register(".pbf", &dl.OSMLoader{})
 
-	engo.Files.Load("maps/1.map")
+	if g.Map == nil && g.MapURL != randomMap {
+		if err := engo.Files.Load(g.MapURL); err != nil {
+			log.Println("Unable to load map:", err)
+		}
+	}
 	engo.Files.Load("fonts/Roboto-Regular.ttf")
 }
 
+// currentMap returns the map to play on, loading it the first time
+func (g *Game) currentMap() *dl.Map {
+	if g.Map == nil {
+		g.Map = g.loadMap()
+	}
+	return g.Map
+}
+
+// loadMap returns the map to play on, falling back to a procedurally generated one
+func (g *Game) loadMap() *dl.Map {
+	if g.MapURL == randomMap {
+		return g.generateMap()
+	}
+
+	mResource, err := engo.Files.Resource(g.MapURL)
+	if err != nil {
+		log.Println("Using a random map instead:", err)
+		return g.generateMap()
+	}
+
+	m, ok := mResource.(*dl.Map)
+	if !ok {
+		log.Printf("Using a random map instead: map resource is not of type *Map: %s", g.MapURL)
+		return g.generateMap()
+	}
+	return m
+}
+
+func (g *Game) generateMap() *dl.Map {
+	if g.Seed == 0 {
+		g.Seed = time.Now().UnixNano()
+	}
+	log.Println("Generating map with seed", g.Seed)
+	return dl.GenerateCity(dl.DefaultCityOptions(g.Seed))
+}
+
 func (g *Game) Setup(w *ecs.World) {
 	common.SetBackground(color.NRGBA{100, 100, 100, 255})
 	rs := &common.RenderSystem{}
 	ms := &common.MouseSystem{}
 	iss := &dl.IncidentSystem{}
-	ds := &dl.DispatchSystem{}
+	ds := &dl.DispatchSystem{Seed: g.Seed}
 
 	w.AddSystem(&common.CameraSystem{})
 	w.AddSystem(rs)
@@ -47,76 +109,29 @@ func (g *Game) Setup(w *ecs.World) {
 	//w.AddSystem(&common.EdgeScroller{EdgeScrollSpeed, EdgeWidth})
 	w.AddSystem(&common.MouseZoomer{ZoomSpeed})
 	w.AddSystem(&dl.IncidentDebugSystem{})
+	w.AddSystem(&dl.IsochroneDebugSystem{})
+	w.AddSystem(&dl.RadioLogSystem{})
 	w.AddSystem(ds)
 	w.AddSystem(iss)
+	w.AddSystem(&SceneSwitcher{Scene: TheEditor.Type()})
 
-	/*
-		mResource, err := engo.Files.Resource("maps/1.map")
-		if err != nil {
-			panic(err)
-		}
-
-		m, ok := mResource.(*dl.Map)
-		if !ok {
-			panic(fmt.Errorf("Map resource is not of type *Map: %s", "maps/1.map"))
-		}
-	*/
-
-	m := dl.RandomMap(10, 10, 100, 100)
+	m := g.currentMap()
 	m.Initialize()
 
-	for _, node := range m.Nodes {
-		type mapEntity struct {
-			ecs.BasicEntity
-			common.RenderComponent
-			common.SpaceComponent
-		}
-
-		/* We can add it if we want to enable it for debugging
-		me := mapEntity{
-			BasicEntity:     ecs.NewBasic(),
-			RenderComponent: common.RenderComponent{Drawable: nodeGraphic, Color: NodeColor},
-			SpaceComponent:  common.SpaceComponent{node.Location, NodeSize, NodeSize, 0},
-		}
-
-		rs.Add(&me.BasicEntity, &me.RenderComponent, &me.SpaceComponent)
-		*/
+	w.AddSystem(&RoadRenderSystem{Map: m})
+	w.AddSystem(&dl.ZoneOverlaySystem{Map: m})
+	w.AddSystem(&dl.POIRenderSystem{Map: m})
 
-		// Render roads - TODO: optimize
-		for _, conn := range node.ConnectedTo {
-			type roadEntity struct {
-				ecs.BasicEntity
-				common.RenderComponent
-				common.SpaceComponent
-			}
-			loc, length, rot := ComputeRoad(node.Location, m.Node(conn).Location, ui.RoadSize)
-
-			road := roadEntity{
-				BasicEntity:     ecs.NewBasic(),
-				RenderComponent: common.RenderComponent{Drawable: ui.RoadGraphic, Color: ui.RoadColor},
-				SpaceComponent:  common.SpaceComponent{loc, length, ui.RoadSize, rot},
-			}
-			rs.Add(&road.BasicEntity, &road.RenderComponent, &road.SpaceComponent)
-
-			/*
-				connNode := m.Node(conn)
-				var found bool
-				for _, n := range connNode.ConnectedTo {
-					if n == node.ID {
-						found = true
-						break
-					}
-				}
-				if !found {
-					connNode.ConnectedTo = append(connNode.ConnectedTo, node.ID)
-				}
-			*/
-		}
+	profiles, err := dl.LoadTrafficProfiles("assets/traffic.yaml")
+	if err != nil {
+		log.Println("Unable to load traffic profiles, using the defaults:", err)
 	}
+	w.AddSystem(&dl.TrafficSystem{Map: m, Profiles: profiles, Hour: float32(*hourFlag)})
 
 	// Now let's move on to the "incidents"
 	start := engo.Point{100, 100}
 	goal := engo.Point{500, 100}
+	accident := engo.Point{300, 100}
 	incidents := []dl.IncidentComponent{
 		{Location: &start,
 			Incident: &dl.IncidentCarSpeeding{
@@ -128,36 +143,52 @@ func (g *Game) Setup(w *ecs.World) {
 			{&engo.Point{130, 104}, "IncidentCarAccident", 1, 1, dl.UrgencyNotUrgent},
 			{&engo.Point{165, 102}, "IncidentCarAccident", 1, 2, dl.UrgencyUrgent},
 		}},
+		{Location: &accident,
+			Incident: &dl.IncidentCarAccident{}},
 	}
 
 	for id := range incidents {
 		iss.Spawn(incidents[id])
 	}
 
-	// Now let's see if we can get some police ready for the incident
+	// Now let's see if we can get some units of every agency ready for the incident
 
-	unitTypes, err := dl.LoadPoliceUnits("assets/units/police.yaml")
+	unitTypes, err := dl.LoadUnitCatalogs("assets/units")
 	if err != nil {
 		panic(err)
 	}
 
+	// Units start at the bases of their agency, or at these locations if the map has none
+	bases := make(map[dl.POIKind]int)
 	unitLocations := []engo.Point{
 		{300, 300},
 		{500, 500},
 		{400, 100},
 		{900, 900},
+		{600, 300},
+		{300, 600},
+		{700, 700},
 	}
-	units := []dl.PoliceComponent{
-		{Unit: unitTypes.ByName("Car")},
-		{Unit: unitTypes.ByName("Car")},
-		{Unit: unitTypes.ByName("Bike Light")},
-		{Unit: unitTypes.ByName("Van w/ Cells")},
+	var units []dl.PoliceComponent
+	for _, name := range []string{"Car", "Car", "Bike Light", "Van w/ Cells", "Ambulance", "Fire Engine", "Tow Truck"} {
+		unitType, err := unitTypes.ByName(name)
+		if err != nil {
+			panic(err)
+		}
+		units = append(units, dl.PoliceComponent{Unit: unitType})
 	}
 	for i, unit := range units {
+		if kind, ok := unit.Unit.Agency.Base(); ok {
+			if stations := m.POIsOf(kind); len(stations) > 0 {
+				unit.Station = stations[bases[kind]%len(stations)]
+				bases[kind]++
+				unitLocations[i] = m.Entrance(unit.Station).Point
+			}
+		}
 		pe := dl.PoliceEntity{
 			BasicEntity:     ecs.NewBasic(),
-			RenderComponent: common.RenderComponent{Drawable: ui.PoliceGraphic, Color: ui.PoliceColor, TextureAlignment: common.AlignCenter},
-			SpaceComponent:  common.SpaceComponent{unitLocations[i], ui.PoliceSize * unit.Unit.Size, ui.PoliceSize * unit.Unit.Size, 0},
+			RenderComponent: common.RenderComponent{Drawable: ui.PoliceGraphic, Color: unit.Status.Color(), TextureAlignment: common.AlignCenter},
+			SpaceComponent:  common.SpaceComponent{m.SnapToRoad(unitLocations[i]).Point, ui.PoliceSize * unit.Unit.Size, ui.PoliceSize * unit.Unit.Size, 0},
 			PoliceComponent: unit,
 		}
 		pe.SetZIndex(ui.PoliceZIndex)
@@ -168,40 +199,26 @@ func (g *Game) Setup(w *ecs.World) {
 	}
 }
 
-// ComputeRoad computes data needed to position roads between two points
-func ComputeRoad(from, to engo.Point, height float32) (engo.Point, float32, float32) {
-	roadLength := math.Sqrt(
-		math.Pow(from.X-to.X, 2) +
-			math.Pow(from.Y-to.Y, 2),
-	)
-
-	a := to.Y - from.Y
-	b := roadLength
-	c := to.X - from.X
-	if c == 0 {
-		if a > 0 {
-			return from, roadLength, 90
-		} else {
-			return from, roadLength, -90
-		}
-	}
-
-	rotation_rad := math.Acos((-math.Pow(a, 2) + math.Pow(b, 2) + math.Pow(c, 2)) / (2 * b * c))
-	rotation := 180 * (rotation_rad / math.Pi)
-
-	return from, roadLength, -rotation
-}
-
 func (g *Game) Type() string {
 	return "GameScene"
 }
 
 func main() {
+	flag.Parse()
+	TheGame.MapURL = *mapFlag
+	TheGame.Seed = *seedFlag
+
 	opts := engo.RunOptions{
 		Title:          title,
 		StandardInputs: true,
 		Height:         860,
 		Width:          800,
 	}
-	engo.Run(opts, TheGame)
+	engo.RegisterScene(TheGame)
+	engo.RegisterScene(TheEditor)
+	if *editFlag {
+		engo.Run(opts, TheEditor)
+	} else {
+		engo.Run(opts, TheGame)
+	}
 }
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"image/color"
+
+	"engo.io/ecs"
+	"engo.io/engo"
+	"engo.io/engo/common"
+	"github.com/EtienneBruines/ultimate-dispatcher/dl"
+	"github.com/EtienneBruines/ultimate-dispatcher/ui"
+	"github.com/luxengine/math"
+)
+
+// roadChunkSize is the size of the area of which all roads are drawn at once
+const roadChunkSize = 1000
+
+// roadCell is an area of the size of a chunk. Roads belong to the cell their middle is in.
+type roadCell struct {
+	X, Y int32
+}
+
+type roadChunkKey struct {
+	roadCell
+	Color color.Color
+}
+
+type roadChunk struct {
+	ecs.BasicEntity
+	common.RenderComponent
+	common.SpaceComponent
+
+	// triangles are the corners of the triangles making up the roads, in map coordinates
+	triangles []engo.Point
+}
+
+// RoadRenderSystem draws the roads of a map. Instead of drawing every road by itself, the map is divided into chunks
+// and all roads of the same color in a chunk are drawn at once. Whenever roads change, only the chunks they are in
+// are rebuilt. Both the game and the editor draw their roads using it.
+type RoadRenderSystem struct {
+	Map *dl.Map
+	// Color returns the color of a road, which shows whether it's closed or slowed down if not set
+	Color func(*dl.Road) color.Color
+
+	rs      *common.RenderSystem
+	version uint64
+	chunks  map[roadChunkKey]*roadChunk
+	// cells are the roads drawn in each cell, and drawn the cell each road is drawn in
+	cells map[roadCell]map[*dl.Road]dl.RoadSegment
+	drawn map[*dl.Road]roadCell
+}
+
+func (r *RoadRenderSystem) New(w *ecs.World) {
+	for _, system := range w.Systems() {
+		if rs, ok := system.(*common.RenderSystem); ok {
+			r.rs = rs
+		}
+	}
+	r.rebuild()
+}
+
+func (r *RoadRenderSystem) Remove(b ecs.BasicEntity) {}
+
+func (r *RoadRenderSystem) Update(dt float32) {
+	if r.Map.Version() == r.version {
+		return
+	}
+	if changes, ok := r.Map.ChangedRoads(r.version); ok {
+		r.update(changes)
+	} else {
+		r.rebuild()
+	}
+}
+
+// rebuild draws all roads of the map again
+func (r *RoadRenderSystem) rebuild() {
+	r.version = r.Map.Version()
+	for _, chunk := range r.chunks {
+		r.rs.Remove(chunk.BasicEntity)
+	}
+	r.chunks = make(map[roadChunkKey]*roadChunk)
+	r.cells = make(map[roadCell]map[*dl.Road]dl.RoadSegment)
+	r.drawn = make(map[*dl.Road]roadCell)
+
+	for _, node := range r.Map.Nodes {
+		for _, road := range node.Roads {
+			if to := r.Map.Node(road.To); to != nil {
+				r.add(dl.RoadSegment{From: node, To: to, Road: road})
+			}
+		}
+	}
+	for cell := range r.cells {
+		r.draw(cell)
+	}
+}
+
+// update redraws the cells of the roads which changed, both where they were and where they are now
+func (r *RoadRenderSystem) update(changes []dl.RoadChange) {
+	r.version = r.Map.Version()
+	dirty := make(map[roadCell]struct{})
+	for _, change := range changes {
+		seg := change.RoadSegment
+		if cell, ok := r.drawn[change.Road]; ok {
+			if seg.From == nil {
+				// Only the restrictions of the road changed
+				seg = r.cells[cell][change.Road]
+			}
+			delete(r.cells[cell], change.Road)
+			delete(r.drawn, change.Road)
+			dirty[cell] = struct{}{}
+		}
+		if r.Map.HasRoad(seg) {
+			dirty[r.add(seg)] = struct{}{}
+		}
+	}
+	for cell := range dirty {
+		r.draw(cell)
+	}
+}
+
+func (r *RoadRenderSystem) add(seg dl.RoadSegment) roadCell {
+	cell := roadCell{
+		X: int32(math.Floor((seg.From.Location.X + seg.To.Location.X) / 2 / roadChunkSize)),
+		Y: int32(math.Floor((seg.From.Location.Y + seg.To.Location.Y) / 2 / roadChunkSize)),
+	}
+	if r.cells[cell] == nil {
+		r.cells[cell] = make(map[*dl.Road]dl.RoadSegment)
+	}
+	r.cells[cell][seg.Road] = seg
+	r.drawn[seg.Road] = cell
+	return cell
+}
+
+// draw replaces the chunks of the cell
+func (r *RoadRenderSystem) draw(cell roadCell) {
+	for key, chunk := range r.chunks {
+		if key.roadCell == cell {
+			r.rs.Remove(chunk.BasicEntity)
+			delete(r.chunks, key)
+		}
+	}
+
+	roadColor := r.Color
+	if roadColor == nil {
+		roadColor = defaultRoadColor
+	}
+	triangles := make(map[roadChunkKey][]engo.Point)
+	for _, seg := range r.cells[cell] {
+		// Two-way roads are drawn only once
+		if seg.To.ID < seg.From.ID && seg.To.Road(seg.From.ID) != nil {
+			continue
+		}
+		key := roadChunkKey{cell, roadColor(seg.Road)}
+		triangles[key] = appendRoadTriangles(triangles[key], seg.From.Location, seg.To.Location, ui.RoadSize)
+	}
+
+	for key, points := range triangles {
+		if len(points) == 0 {
+			continue
+		}
+		chunk := newRoadChunk(points, key.Color)
+		r.chunks[key] = chunk
+		r.rs.Add(&chunk.BasicEntity, &chunk.RenderComponent, &chunk.SpaceComponent)
+	}
+	if len(r.cells[cell]) == 0 {
+		delete(r.cells, cell)
+	}
+}
+
+func defaultRoadColor(road *dl.Road) color.Color {
+	switch {
+	case road.Closed():
+		return ui.RoadColorClosed
+	case road.SpeedFactor() < 1:
+		return ui.RoadColorSlow
+	}
+	return ui.RoadColor
+}
+
+func newRoadChunk(triangles []engo.Point, c color.Color) *roadChunk {
+	min, max := triangles[0], triangles[0]
+	for _, p := range triangles {
+		min.X, min.Y = math.Min(min.X, p.X), math.Min(min.Y, p.Y)
+		max.X, max.Y = math.Max(max.X, p.X), math.Max(max.Y, p.Y)
+	}
+	width, height := math.Max(max.X-min.X, 1), math.Max(max.Y-min.Y, 1)
+
+	// The points of the drawable are relative to the size of the chunk
+	relative := make([]engo.Point, len(triangles))
+	for i, p := range triangles {
+		relative[i] = engo.Point{(p.X - min.X) / width, (p.Y - min.Y) / height}
+	}
+
+	return &roadChunk{
+		BasicEntity:     ecs.NewBasic(),
+		RenderComponent: common.RenderComponent{Drawable: common.ComplexTriangles{Points: relative}, Color: c},
+		SpaceComponent:  common.SpaceComponent{Position: min, Width: width, Height: height},
+		triangles:       triangles,
+	}
+}
+
+// appendRoadTriangles appends the two triangles making up the rectangle of the given width between the points. The
+// corners are computed from the normal of the road, so unlike a rotated rectangle it works for roads in any direction.
+func appendRoadTriangles(triangles []engo.Point, from, to engo.Point, width float32) []engo.Point {
+	length := from.PointDistance(to)
+	if length == 0 {
+		return triangles
+	}
+
+	// The normal of the road, of half its width
+	nx := -(to.Y - from.Y) / length * width / 2
+	ny := (to.X - from.X) / length * width / 2
+
+	a := engo.Point{from.X + nx, from.Y + ny}
+	b := engo.Point{from.X - nx, from.Y - ny}
+	c := engo.Point{to.X + nx, to.Y + ny}
+	d := engo.Point{to.X - nx, to.Y - ny}
+	return append(triangles, a, b, c, b, d, c)
+}
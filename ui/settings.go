@@ -12,6 +12,7 @@ const (
 	IncidentReportSize float32 = 1 * NodeSize
 	PoliceSize         float32 = 2 * NodeSize
 	WaypointSize       float32 = 1 * NodeSize
+	POISize            float32 = 1.5 * NodeSize
 
 	TooltipLineHeight float32 = 24
 	PoliceZIndex      float32 = 500
@@ -20,6 +21,8 @@ const (
 var (
 	NodeColor                = color.NRGBA{0, 255, 255, 255}
 	RoadColor                = color.White
+	RoadColorSlow            = color.NRGBA{255, 165, 0, 255}
+	RoadColorClosed          = color.NRGBA{255, 0, 0, 255}
 	IncidentColor            = color.NRGBA{0, 0, 0, 255}
 	IncidentColorHover       = color.NRGBA{153, 0, 0, 255}
 	IncidentReportColor      = color.NRGBA{255, 0, 0, 128}
@@ -32,6 +35,41 @@ var (
 	TooltipColorBorder       = color.Black
 	WaypointColor            = color.NRGBA{0, 255, 0, 150}
 
+	// IsochroneColors are the colors of the areas reachable within increasingly long times
+	IsochroneColors = []color.Color{
+		color.NRGBA{0, 255, 0, 60},
+		color.NRGBA{255, 255, 0, 45},
+		color.NRGBA{255, 0, 0, 30},
+	}
+
+	// ZoneColors are the colors of precincts, patrol beats and high-crime areas
+	ZoneColors = []color.Color{
+		color.NRGBA{0, 0, 255, 25},
+		color.NRGBA{0, 200, 255, 35},
+		color.NRGBA{255, 0, 0, 45},
+	}
+
+	// POIColors are the colors of police stations, hospitals, jails, banks, schools, bars, fire stations and depots
+	POIColors = []color.Color{
+		color.NRGBA{0, 0, 200, 255},
+		color.NRGBA{255, 255, 255, 255},
+		color.NRGBA{60, 60, 60, 255},
+		color.NRGBA{255, 215, 0, 255},
+		color.NRGBA{255, 140, 0, 255},
+		color.NRGBA{160, 32, 240, 255},
+		color.NRGBA{220, 20, 20, 255},
+		color.NRGBA{120, 80, 40, 255},
+	}
+
+	// StatusColors are the colors of units which are available, en route, on scene, transporting and out of service
+	StatusColors = []color.Color{
+		PoliceColor,
+		color.NRGBA{255, 165, 0, 220},
+		color.NRGBA{220, 0, 0, 220},
+		color.NRGBA{140, 0, 200, 220},
+		color.NRGBA{120, 120, 120, 180},
+	}
+
 	NodeGraphic           = common.Circle{}
 	RoadGraphic           = common.Rectangle{}
 	IncidentGraphic       = common.Circle{}
@@ -39,4 +77,5 @@ var (
 	PoliceGraphic         = common.Circle{}
 	TooltipGraphic        = common.Rectangle{BorderWidth: 1, BorderColor: TooltipColorBorder}
 	WaypointGraphic       = common.Rectangle{}
+	POIGraphic            = common.Rectangle{BorderWidth: 1, BorderColor: color.Black}
 )